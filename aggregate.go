@@ -0,0 +1,188 @@
+package sqlogger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+)
+
+// The maximum number of buckets of an aggregation.
+const maxAggBuckets = 100_000
+
+// GroupBy is the key splitting the counts of the buckets of an aggregation.
+type GroupBy int
+
+const (
+	// GroupByNone does not split the counts.
+	GroupByNone GroupBy = iota
+	// GroupByLevel counts the entries of every level, keyed by the name of the level.
+	GroupByLevel
+	// GroupBySourceFile counts the entries logged from every file, keyed by the
+	// file relative to the working directory. Entries without source have an
+	// empty key.
+	GroupBySourceFile
+)
+
+// AggSpec specifies an aggregation of the entries.
+type AggSpec struct {
+	// Bucket is the width of the time buckets, like time.Minute. The buckets are
+	// aligned to the Unix epoch, so minutes and hours start on the clock in UTC.
+	// Zero counts all the entries in a single bucket, starting at Query.Since.
+	Bucket time.Duration
+
+	// GroupBy splits the counts of every bucket.
+	GroupBy GroupBy
+
+	// Query selects the entries counted. Its Limit is ignored.
+	// With Since and Until, the buckets cover the whole range, including the
+	// empty ones. Otherwise, they go from the first entry to the last one.
+	Query Query
+}
+
+// AggBucket holds the counts of a time bucket of an aggregation.
+type AggBucket struct {
+	Start time.Time
+
+	// Count is the number of times an entry was logged in the bucket, including
+	// the repetitions of coalesced entries, and Errors those of level ERROR or above.
+	Count  int64
+	Errors int64
+
+	// Groups are the counts by the key of AggSpec.GroupBy, nil with GroupByNone.
+	Groups map[string]int64
+}
+
+// ErrorRate returns the fraction of the entries of the bucket which are errors,
+// or zero if it is empty.
+func (b AggBucket) ErrorRate() float64 {
+	if b.Count == 0 {
+		return 0
+	}
+	return float64(b.Errors) / float64(b.Count)
+}
+
+// Aggregate counts the entries selected by spec.Query in time buckets, inside
+// SQLite, like the errors per minute of the last 24 hours:
+//
+//	buckets, err := rd.Aggregate(ctx, sqlogger.AggSpec{
+//		Bucket:  time.Minute,
+//		GroupBy: sqlogger.GroupByLevel,
+//		Query:   sqlogger.Query{Since: time.Now().Add(-24 * time.Hour), Until: time.Now()},
+//	})
+//
+// The buckets are returned in chronological order.
+func (rd *Reader) Aggregate(ctx context.Context, spec AggSpec) ([]AggBucket, error) {
+	if spec.Bucket < 0 {
+		return nil, fmt.Errorf("negative bucket width")
+	}
+	q := spec.Query
+	if spec.Bucket > 0 && !q.Since.IsZero() && !q.Until.IsZero() {
+		if n := q.Until.Sub(q.Since) / spec.Bucket; n > maxAggBuckets {
+			return nil, fmt.Errorf("too many buckets: %d, the maximum is %d", n, maxAggBuckets)
+		}
+	}
+
+	rd.mu.RLock()
+	defer rd.mu.RUnlock()
+
+	width := int64(spec.Bucket)
+	if width == 0 {
+		// A single bucket for all the times
+		width = 1<<63 - 1
+	}
+
+	key, groupBy := "NULL", "bucket"
+	switch spec.GroupBy {
+	case GroupByNone:
+	case GroupByLevel:
+		key, groupBy = "level", "bucket, level"
+	case GroupBySourceFile:
+		key, groupBy = "source_file", "bucket, source_file"
+	default:
+		return nil, fmt.Errorf("unknown aggregation key %d", spec.GroupBy)
+	}
+
+	where, args := queryWhere(q, rd.indexedColumns)
+	stmt := fmt.Sprintf("SELECT (epoch_secs * 1000000000 + nanos) / ? AS bucket, %s, SUM(repeat_count), "+
+		"SUM(CASE WHEN level >= ? THEN repeat_count ELSE 0 END) FROM entries%s GROUP BY %s", key, where, groupBy)
+	args = append([]any{width, int(slog.LevelError)}, args...)
+
+	buckets := map[int64]*AggBucket{}
+	for _, f := range rd.files {
+		rows, err := f.db.QueryContext(ctx, stmt, args...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: aggregating log entries: %w", f.name, err)
+		}
+		for rows.Next() {
+			var index, count, errorCount int64
+			var group any
+			if err := rows.Scan(&index, &group, &count, &errorCount); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("%s: reading aggregation: %w", f.name, err)
+			}
+
+			b := buckets[index]
+			if b == nil {
+				b = &AggBucket{Start: time.Unix(0, index*width)}
+				buckets[index] = b
+			}
+			b.Count += count
+			b.Errors += errorCount
+
+			switch spec.GroupBy {
+			case GroupByLevel:
+				b.addGroup(slog.Level(group.(int64)).String(), count)
+			case GroupBySourceFile:
+				name, _ := group.(string)
+				b.addGroup(name, count)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("%s: reading aggregation: %w", f.name, err)
+		}
+	}
+
+	if spec.Bucket == 0 {
+		if b := buckets[0]; b != nil {
+			b.Start = q.Since
+			return []AggBucket{*b}, nil
+		}
+		return []AggBucket{{Start: q.Since}}, nil
+	}
+
+	// The empty buckets are filled in, for continuous series
+	var first, last int64
+	if !q.Since.IsZero() && !q.Until.IsZero() {
+		first = q.Since.UnixNano() / width
+		last = (q.Until.UnixNano() - 1) / width
+	} else if len(buckets) > 0 {
+		indexes := slices.Sorted(maps.Keys(buckets))
+		first, last = indexes[0], indexes[len(indexes)-1]
+		if last-first >= maxAggBuckets {
+			return nil, fmt.Errorf("too many buckets: %d, the maximum is %d", last-first+1, maxAggBuckets)
+		}
+	} else {
+		return nil, nil
+	}
+
+	result := make([]AggBucket, 0, last-first+1)
+	for index := first; index <= last; index++ {
+		if b := buckets[index]; b != nil {
+			result = append(result, *b)
+		} else {
+			result = append(result, AggBucket{Start: time.Unix(0, index*width)})
+		}
+	}
+	return result, nil
+}
+
+func (b *AggBucket) addGroup(key string, count int64) {
+	if b.Groups == nil {
+		b.Groups = map[string]int64{}
+	}
+	b.Groups[key] += count
+}
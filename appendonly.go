@@ -0,0 +1,161 @@
+package sqlogger
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The codes of the SQLite authorizer, defined here as the constants of the driver
+// need cgo. See https://www.sqlite.org/c3ref/c_alter_table.html
+const (
+	authOK   = 0
+	authDeny = 1
+
+	authDelete          = 9
+	authDropIndex       = 10
+	authDropTable       = 11
+	authDropTempIndex   = 12
+	authDropTempTable   = 13
+	authDropTempTrigger = 14
+	authDropTempView    = 15
+	authDropTrigger     = 16
+	authDropView        = 17
+	authInsert          = 18
+	authPragma          = 19
+	authRead            = 20
+	authSelect          = 21
+	authTransaction     = 22
+	authUpdate          = 23
+	authDropVTable      = 30
+	authFunction        = 31
+	authSavepoint       = 32
+)
+
+// The pragmas which the insert-only connections of an append-only store can run.
+var appendOnlyPragmas = map[string]bool{
+	"journal_mode":   true,
+	"synchronous":    true,
+	"busy_timeout":   true,
+	"wal_checkpoint": true,
+	"cipher_version": true,
+}
+
+// openLiveDB opens a new live database file with the given name and creates its
+// schema with schemaSQL.
+func (s *SQLiteStore) openLiveDB(name string, schemaSQL string) (*sql.DB, error) {
+	if s.appendOnly {
+		return s.openAppendOnlyDB(name, schemaSQL)
+	}
+
+	db, err := s.openDB(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.createSchema(db, schemaSQL); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// openAppendOnlyDB creates the live database file of an append-only store, which
+// must not exist, and opens it for inserts only. The schema is created with a
+// connection which can not drop, delete or update, and the tables get triggers
+// rejecting any update or delete made later by other connections.
+func (s *SQLiteStore) openAppendOnlyDB(name string, schemaSQL string) (*sql.DB, error) {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("creating append-only log file: %w", err)
+	}
+	f.Close()
+
+	db, err := s.openAuthorizedDB(name, name, schemaAuthorizer)
+	if err != nil {
+		return nil, err
+	}
+	err = s.createSchema(db, schemaSQL)
+	if err == nil {
+		err = createAppendOnlyTriggers(db)
+	}
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.openAuthorizedDB(name, name, insertAuthorizer)
+}
+
+// createAppendOnlyTriggers makes every table of the database reject updates and deletes.
+func createAppendOnlyTriggers(db *sql.DB) error {
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		return err
+	}
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		for _, op := range []string{"UPDATE", "DELETE"} {
+			stmt := fmt.Sprintf("CREATE TRIGGER %s_no_%s BEFORE %s ON %s BEGIN SELECT RAISE(ABORT, 'append-only log'); END",
+				table, strings.ToLower(op), op, table)
+			if _, err := db.Exec(stmt); err != nil {
+				return fmt.Errorf("protecting table %s: %w", table, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// schemaAuthorizer lets the schema be created, denying the destructive operations.
+// The updates of the schema table itself, made by ALTER TABLE, are allowed.
+func schemaAuthorizer(op int, arg1, arg2, arg3 string) int {
+	switch op {
+	case authDelete, authUpdate:
+		if !strings.HasPrefix(arg1, "sqlite_") {
+			return authDeny
+		}
+	case authDropIndex, authDropTable, authDropTrigger, authDropView,
+		authDropTempIndex, authDropTempTable, authDropTempTrigger,
+		authDropTempView, authDropVTable:
+		return authDeny
+	}
+	return authOK
+}
+
+// insertAuthorizer lets the connections only insert and read the entries.
+func insertAuthorizer(op int, arg1, arg2, arg3 string) int {
+	switch op {
+	case authInsert, authRead, authSelect, authFunction,
+		authTransaction, authSavepoint:
+		return authOK
+	case authPragma:
+		if appendOnlyPragmas[strings.ToLower(arg1)] {
+			return authOK
+		}
+	}
+	return authDeny
+}
+
+// seal makes a database file read-only once it is no longer written.
+func seal(name string) error {
+	if err := os.Chmod(name, 0o444); err != nil {
+		return fmt.Errorf("sealing log file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package sqlogger
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultConsoleQueueSize = 1024
+
+// consoleJob is a line waiting for the console goroutine: a record to render, or
+// text already rendered, like the notices of coalesced repeats.
+type consoleJob struct {
+	h     *SQLogger
+	line  consoleLine
+	attrs []byte
+	keys  []int
+
+	text []byte
+}
+
+// asyncConsole renders and writes the console lines in its own goroutine, so the
+// logging goroutines do not wait for the terminal. When the queue is full, the
+// oldest lines are dropped: the store has all the entries.
+type asyncConsole struct {
+	w       io.Writer
+	jobs    chan consoleJob
+	dropped atomic.Uint64
+	done    chan struct{}
+
+	// Held for reading while queuing, so jobs is not closed meanwhile
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newAsyncConsole(w io.Writer, size int) *asyncConsole {
+	c := &asyncConsole{
+		w:    w,
+		jobs: make(chan consoleJob, size),
+		done: make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+func (c *asyncConsole) run() {
+	defer close(c.done)
+
+	var buf []byte
+	for job := range c.jobs {
+		if job.h == nil {
+			c.w.Write(job.text)
+			continue
+		}
+		buf = job.h.appendConsole(buf[:0], &job.line, job.attrs, job.keys)
+		c.w.Write(buf)
+	}
+}
+
+// enqueue queues a line, dropping the oldest one if the queue is full.
+func (c *asyncConsole) enqueue(job consoleJob) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return
+	}
+
+	for {
+		select {
+		case c.jobs <- job:
+			return
+		default:
+		}
+		select {
+		case <-c.jobs:
+			c.dropped.Add(1)
+		default:
+		}
+	}
+}
+
+// Write queues text already rendered, keeping its order with the records.
+func (c *asyncConsole) Write(p []byte) (int, error) {
+	c.enqueue(consoleJob{text: bytes.Clone(p)})
+	return len(p), nil
+}
+
+// close writes the lines queued and stops the goroutine.
+func (c *asyncConsole) close() {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.jobs)
+	}
+	c.mu.Unlock()
+	<-c.done
+}
@@ -0,0 +1,66 @@
+package sqlogger
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// flattenAttrs returns the attributes added to the handler with WithAttrs and those
+// of the record as a flat list, with the keys qualified by the names of their
+// groups, like "req.header.host". Empty attributes and groups are omitted.
+func (h *SQLogger) flattenAttrs(r slog.Record) []slog.Attr {
+	var attrs []slog.Attr
+
+	for _, goa := range h.goas {
+		for _, a := range goa.attrs {
+			attrs = appendFlatAttr(attrs, goa.prefix, a)
+		}
+	}
+
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = appendFlatAttr(attrs, h.prefix, a)
+		return true
+	})
+
+	return attrs
+}
+
+func appendFlatAttr(attrs []slog.Attr, prefix string, a slog.Attr) []slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return attrs
+	}
+
+	if a.Value.Kind() != slog.KindGroup {
+		a.Key = prefix + a.Key
+		return append(attrs, a)
+	}
+
+	// The attributes of a group with an empty key are inlined
+	if a.Key != "" {
+		prefix += a.Key + "."
+	}
+	for _, ga := range a.Value.Group() {
+		attrs = appendFlatAttr(attrs, prefix, ga)
+	}
+	return attrs
+}
+
+// attrsText renders a list of flat attributes as key=value pairs separated by spaces.
+func attrsText(attrs []slog.Attr) string {
+	var b strings.Builder
+	for i, a := range attrs {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(a.Key)
+		b.WriteByte('=')
+		if a.Value.Kind() == slog.KindString {
+			b.WriteString(strconv.Quote(a.Value.String()))
+		} else {
+			b.WriteString(a.Value.String())
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,160 @@
+package sqlogger
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// With the attrs table enabled, every attribute of an entry is also stored as a row
+// keyed by the rowid of the entry, so entries can be selected by attribute value.
+// The value column has no type affinity, so values keep their type: integers and
+// floats are stored as numbers, booleans as 0 or 1, times as RFC 3339 text and
+// []byte values as blobs.
+const attrTableSQL = `
+DROP TABLE IF EXISTS attrs;
+
+CREATE TABLE attrs (
+  entry_id INTEGER NOT NULL,
+  key TEXT NOT NULL,
+  value_type TEXT NOT NULL,
+  value
+);
+
+CREATE INDEX attrs_key_value ON attrs (key, value);
+`
+
+// attrColumnValue returns the type name and the value stored for an attribute.
+func attrColumnValue(v slog.Value) (string, any) {
+	if b, ok := binaryValue(v); ok {
+		return "bytes", b
+	}
+	switch v.Kind() {
+	case slog.KindInt64:
+		return "int", v.Int64()
+	case slog.KindUint64:
+		// SQLite integers are signed, so large values are stored as text
+		if u := v.Uint64(); u <= 1<<63-1 {
+			return "int", int64(u)
+		}
+		return "uint", v.String()
+	case slog.KindFloat64:
+		return "float", v.Float64()
+	case slog.KindBool:
+		return "bool", v.Bool()
+	case slog.KindDuration:
+		return "duration", int64(v.Duration())
+	case slog.KindTime:
+		return "time", v.Time().Format(time.RFC3339Nano)
+	case slog.KindString:
+		return "string", v.String()
+	default:
+		return "any", v.String()
+	}
+}
+
+// storedAttrs returns the attributes of the entry written to the attrs table.
+func (s *SQLiteStore) storedAttrs(attrs []slog.Attr) []slog.Attr {
+	if s.attrKeys == nil {
+		return attrs
+	}
+	var stored []slog.Attr
+	for _, a := range attrs {
+		if slices.Contains(s.attrKeys, a.Key) {
+			stored = append(stored, a)
+		}
+	}
+	return stored
+}
+
+// insertAttrs writes the attributes of the entry with the given rowid.
+func insertAttrs(ctx context.Context, tx *sql.Tx, id int64, attrs []slog.Attr) error {
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO attrs (entry_id, key, value_type, value) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("inserting log attributes: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, a := range attrs {
+		valueType, value := attrColumnValue(a.Value)
+		if _, err := stmt.ExecContext(ctx, id, a.Key, valueType, value); err != nil {
+			return fmt.Errorf("inserting log attributes: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Attrs returns the attributes of an entry returned by the Reader, as stored in the
+// attrs table of its file, or nil if the file has no attrs table (see Options.AttrTable).
+// The values have the kind they were logged with, except the uncommon ones, which
+// are strings.
+func (rd *Reader) Attrs(ctx context.Context, e Entry) ([]slog.Attr, error) {
+	rd.mu.RLock()
+	defer rd.mu.RUnlock()
+
+	i := slices.IndexFunc(rd.files, func(f readerFile) bool { return f.name == e.File })
+	if i < 0 {
+		return nil, fmt.Errorf("the file %q of the entry is not read by the Reader", e.File)
+	}
+	db := rd.files[i].db
+
+	var n int
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'attrs'").Scan(&n); err != nil {
+		return nil, fmt.Errorf("reading log attributes: %w", err)
+	}
+	if n == 0 {
+		return nil, nil
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT key, value_type, value FROM attrs WHERE entry_id = ? ORDER BY rowid", e.ID)
+	if err != nil {
+		return nil, fmt.Errorf("reading log attributes: %w", err)
+	}
+	defer rows.Close()
+
+	var attrs []slog.Attr
+	for rows.Next() {
+		var key, valueType string
+		var value any
+		if err := rows.Scan(&key, &valueType, &value); err != nil {
+			return nil, fmt.Errorf("reading log attributes: %w", err)
+		}
+		attrs = append(attrs, slog.Attr{Key: key, Value: storedAttrValue(valueType, value)})
+	}
+
+	return attrs, rows.Err()
+}
+
+// storedAttrValue converts a value of the attrs table back to the kind it was
+// logged with, as stored by attrColumnValue.
+func storedAttrValue(valueType string, value any) slog.Value {
+	switch v := value.(type) {
+	case int64:
+		switch valueType {
+		case "bool":
+			return slog.BoolValue(v != 0)
+		case "duration":
+			return slog.DurationValue(time.Duration(v))
+		}
+		return slog.Int64Value(v)
+	case float64:
+		return slog.Float64Value(v)
+	case []byte:
+		if valueType == "bytes" {
+			return slog.AnyValue(v)
+		}
+		value = string(v)
+	}
+
+	s := fmt.Sprint(value)
+	if valueType == "time" {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return slog.TimeValue(t)
+		}
+	}
+	return slog.StringValue(s)
+}
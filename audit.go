@@ -0,0 +1,479 @@
+package sqlogger
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// In audit mode every entry stores the SHA-256 of the hash of the previous entry
+// followed by all the columns of the entry, so modifying or deleting any entry
+// breaks the chain.
+// The rows of the attrs and blobs tables belonging to an entry are hashed with it.
+// Each file also records in the chain table the hash its chain starts from, which is
+// the hash of the last entry of the previous file in the rotation.
+const auditSQL = `
+ALTER TABLE entries ADD COLUMN hash BLOB;
+
+DROP TABLE IF EXISTS chain;
+
+CREATE TABLE chain (
+  prev_hash BLOB
+);
+`
+
+// Verifier is implemented by stores which can check the integrity of the entries.
+type Verifier interface {
+	// Verify walks all the entries in chronological order, and returns a
+	// *TamperError describing the first entry which fails verification.
+	Verify(ctx context.Context) error
+}
+
+// TamperError reports an entry which does not match the hash chain.
+type TamperError struct {
+	// File is the database file containing the entry.
+	File string
+
+	// ID is the rowid of the entry in File.
+	ID int64
+
+	Reason string
+}
+
+func (e *TamperError) Error() string {
+	if e.ID == 0 {
+		return fmt.Sprintf("tampered log file %s: %s", e.File, e.Reason)
+	}
+	return fmt.Sprintf("tampered log entry %d in %s: %s", e.ID, e.File, e.Reason)
+}
+
+// rowHash returns the hash of an entry chained to the hash of the previous entry.
+// The entry is hashed by all its stored columns except the hash, in the order of
+// their names and leaving out the NULL values, so the hash is the same computed
+// from the values inserted and from the row read back.
+func rowHash(prev []byte, columns []string, values []any) ([]byte, error) {
+	order := make([]int, len(columns))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return strings.Compare(columns[a], columns[b])
+	})
+
+	var num [8]byte
+	hasher := sha256.New()
+	hasher.Write(prev)
+	for _, i := range order {
+		if columns[i] == "hash" {
+			continue
+		}
+		v, err := driver.DefaultParameterConverter.ConvertValue(values[i])
+		if err != nil {
+			return nil, fmt.Errorf("hashing column %s: %w", columns[i], err)
+		}
+
+		// The values are written with their type and length, so they can not be
+		// shifted from a column to the next
+		var typ byte
+		var data []byte
+		switch v := v.(type) {
+		case nil:
+			continue
+		case int64:
+			typ = 'i'
+			data = binary.BigEndian.AppendUint64(nil, uint64(v))
+		case bool:
+			// SQLite stores booleans as integers
+			typ = 'i'
+			if v {
+				data = binary.BigEndian.AppendUint64(nil, 1)
+			} else {
+				data = binary.BigEndian.AppendUint64(nil, 0)
+			}
+		case float64:
+			// SQLite stores NaN as NULL
+			if math.IsNaN(v) {
+				continue
+			}
+			typ = 'f'
+			data = binary.BigEndian.AppendUint64(nil, math.Float64bits(v))
+		case string:
+			typ = 's'
+			data = []byte(v)
+		case []byte:
+			// A nil slice is stored as NULL. Text and blobs are not told apart, as
+			// they may be read back as either
+			if v == nil {
+				continue
+			}
+			typ = 's'
+			data = v
+		default:
+			return nil, fmt.Errorf("hashing column %s: unsupported type %T", columns[i], v)
+		}
+
+		hasher.Write([]byte(columns[i]))
+		hasher.Write([]byte{0, typ})
+		binary.BigEndian.PutUint64(num[:], uint64(len(data)))
+		hasher.Write(num[:])
+		hasher.Write(data)
+	}
+
+	return hasher.Sum(nil), nil
+}
+
+// sideTables are the tables with rows belonging to the entries, hashed with them
+// as the digest of the hashes of their rows, in the order they were inserted.
+// The digests are hashed under names which can not be columns of the entries table.
+var sideTables = []struct {
+	table   string
+	column  string
+	columns []string
+}{
+	{"attrs", "@attrs", []string{"key", "value_type", "value"}},
+	{"blobs", "@blobs", []string{"key", "value"}},
+}
+
+// entryHash returns the hash of an entry about to be inserted with the given
+// columns, attributes for the attrs table and full values for the blobs table.
+func (s *SQLiteStore) entryHash(columns []string, values []any, attrs []slog.Attr, blobs []slog.Attr) ([]byte, error) {
+	columns, values = slices.Clip(columns), slices.Clip(values)
+
+	if s.attrTable {
+		digest := sha256.New()
+		for _, a := range attrs {
+			valueType, value := attrColumnValue(a.Value)
+			if err := writeRowHash(digest, sideTables[0].columns, []any{a.Key, valueType, value}); err != nil {
+				return nil, err
+			}
+		}
+		columns = append(columns, sideTables[0].column)
+		values = append(values, digest.Sum(nil))
+	}
+
+	if s.blobTable {
+		digest := sha256.New()
+		for _, a := range blobs {
+			if err := writeRowHash(digest, sideTables[1].columns, []any{a.Key, blobColumnValue(a.Value)}); err != nil {
+				return nil, err
+			}
+		}
+		columns = append(columns, sideTables[1].column)
+		values = append(values, digest.Sum(nil))
+	}
+
+	return rowHash(s.chainHash, columns, values)
+}
+
+// writeRowHash adds the hash of a row of a side table to the digest of its entry.
+func writeRowHash(digest hash.Hash, columns []string, values []any) error {
+	h, err := rowHash(nil, columns, values)
+	if err != nil {
+		return err
+	}
+	digest.Write(h)
+	return nil
+}
+
+// sideDigests are the digests of the rows of the side tables of a database, by
+// the rowid of their entry.
+type sideDigests struct {
+	columns []string
+	digests map[string]map[int64]hash.Hash
+}
+
+// readSideDigests reads the rows of the side tables of a database belonging to the
+// entry with the given rowid, or to every entry if id is zero.
+func readSideDigests(ctx context.Context, q interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}, id int64) (*sideDigests, error) {
+	d := &sideDigests{digests: map[string]map[int64]hash.Hash{}}
+
+	for _, t := range sideTables {
+		var n int
+		rows, err := q.QueryContext(ctx, "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", t.table)
+		if err == nil {
+			if rows.Next() {
+				err = rows.Scan(&n)
+			}
+			rows.Close()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s table: %w", t.table, err)
+		}
+		if n == 0 {
+			continue
+		}
+		d.columns = append(d.columns, t.column)
+		d.digests[t.column] = map[int64]hash.Hash{}
+
+		stmt := "SELECT entry_id, " + strings.Join(t.columns, ", ") + " FROM " + t.table
+		var args []any
+		if id != 0 {
+			stmt += " WHERE entry_id = ?"
+			args = append(args, id)
+		}
+		rows, err = q.QueryContext(ctx, stmt+" ORDER BY entry_id, rowid", args...)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s table: %w", t.table, err)
+		}
+
+		values := make([]any, len(t.columns)+1)
+		dest := make([]any, len(values))
+		for i := range dest {
+			dest[i] = &values[i]
+		}
+		for rows.Next() {
+			if err := rows.Scan(dest...); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("reading %s table: %w", t.table, err)
+			}
+			entryId, _ := values[0].(int64)
+			digest := d.digests[t.column][entryId]
+			if digest == nil {
+				digest = sha256.New()
+				d.digests[t.column][entryId] = digest
+			}
+			if err := writeRowHash(digest, t.columns, values[1:]); err != nil {
+				rows.Close()
+				return nil, err
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading %s table: %w", t.table, err)
+		}
+	}
+
+	return d, nil
+}
+
+// with returns the columns and values of the entry with the given rowid followed
+// by the digests of its rows in the side tables, for rowHash.
+func (d *sideDigests) with(id int64, columns []string, values []any) ([]string, []any) {
+	columns, values = slices.Clip(columns), slices.Clip(values)
+	for _, column := range d.columns {
+		digest := d.digests[column][id]
+		if digest == nil {
+			digest = sha256.New()
+		}
+		columns = append(columns, column)
+		values = append(values, digest.Sum(nil))
+	}
+	return columns, values
+}
+
+// repeatLastChained updates the repeat count of the last entry in audit mode,
+// hashing it again. Nothing is chained to the last entry yet, so the chain stays
+// valid.
+func (s *SQLiteStore) repeatLastChained(count int64, last time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("updating repeated log record: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE entries SET repeat_count = ?, last_epoch_secs = ?, last_nanos = ? WHERE rowid = ?",
+		count, last.Unix(), last.Nanosecond(), s.lastRowId)
+	if err != nil {
+		return fmt.Errorf("updating repeated log record: %w", err)
+	}
+
+	rows, err := tx.Query("SELECT * FROM entries WHERE rowid = ?", s.lastRowId)
+	if err != nil {
+		return fmt.Errorf("updating repeated log record: %w", err)
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return fmt.Errorf("updating repeated log record: %w", err)
+	}
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range dest {
+		dest[i] = &values[i]
+	}
+	if !rows.Next() {
+		rows.Close()
+		return fmt.Errorf("updating repeated log record: entry %d not found", s.lastRowId)
+	}
+	err = rows.Scan(dest...)
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("updating repeated log record: %w", err)
+	}
+
+	side, err := readSideDigests(context.Background(), tx, s.lastRowId)
+	if err != nil {
+		return fmt.Errorf("updating repeated log record: %w", err)
+	}
+	columns, values = side.with(s.lastRowId, columns, values)
+
+	hash, err := rowHash(s.lastPrevHash, columns, values)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE entries SET hash = ? WHERE rowid = ?", hash, s.lastRowId); err != nil {
+		return fmt.Errorf("updating repeated log record: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("updating repeated log record: %w", err)
+	}
+
+	s.chainHash = hash
+	return nil
+}
+
+// startChain prepares a freshly created database to store chained entries.
+func startChain(db *sql.DB, prev []byte) error {
+	if _, err := db.Exec(auditSQL); err != nil {
+		return fmt.Errorf("creating audit schema: %w", err)
+	}
+	if _, err := db.Exec("INSERT INTO chain (prev_hash) VALUES (?)", prev); err != nil {
+		return fmt.Errorf("starting hash chain: %w", err)
+	}
+	return nil
+}
+
+// lastHash returns the hash of the last entry in the file with the given name,
+// or nil if the name is empty or the file has no chained entries.
+func (s *SQLiteStore) lastHash(name string) ([]byte, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	db, closeDB, err := s.openLogFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer closeDB()
+
+	var hash []byte
+	err = db.QueryRow("SELECT hash FROM entries ORDER BY rowid DESC LIMIT 1").Scan(&hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		// An empty file continues the chain of the file before it
+		err = db.QueryRow("SELECT prev_hash FROM chain").Scan(&hash)
+	}
+	if err != nil {
+		// A file written without audit mode does not start a chain
+		return nil, nil
+	}
+
+	return hash, nil
+}
+
+// Verify checks the hash chain across all the files in the rotation, from the
+// oldest to the live one. The chain of the oldest file is trusted as the anchor,
+// because the entries before it have been discarded by rotation.
+func (s *SQLiteStore) Verify(ctx context.Context) error {
+	if !s.audit {
+		return fmt.Errorf("the store is not in audit mode")
+	}
+
+	if s.single() {
+		return s.verifySingle(ctx)
+	}
+
+	names, err := s.ringOrder()
+	if err != nil {
+		return err
+	}
+
+	var prev []byte
+	first := true
+
+	for _, name := range names {
+		last, err := s.verifyFile(ctx, name, prev, first)
+		if err != nil {
+			return err
+		}
+		prev = last
+		first = false
+	}
+
+	return nil
+}
+
+// verifyFile checks the chain of a single file, which must start from prev unless
+// it is the anchor. It returns the hash of the last entry.
+func (s *SQLiteStore) verifyFile(ctx context.Context, name string, prev []byte, anchor bool) ([]byte, error) {
+	db, closeDB, err := s.openLogFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer closeDB()
+
+	return verifyDB(ctx, db, name, prev, anchor)
+}
+
+// verifyDB checks the chain of the entries of a database, with the given name
+// for the errors.
+func verifyDB(ctx context.Context, db *sql.DB, name string, prev []byte, anchor bool) ([]byte, error) {
+	var start []byte
+	if err := db.QueryRowContext(ctx, "SELECT prev_hash FROM chain").Scan(&start); err != nil {
+		return nil, &TamperError{File: name, Reason: "missing chain start: " + err.Error()}
+	}
+	if !anchor && !bytes.Equal(start, prev) {
+		return nil, &TamperError{File: name, Reason: "chain start does not match the previous file"}
+	}
+
+	// The side tables are read first, as the database may have a single connection
+	side, err := readSideDigests(ctx, db, 0)
+	if err != nil {
+		return nil, fmt.Errorf("reading entries of %s: %w", name, err)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT rowid, * FROM entries ORDER BY rowid")
+	if err != nil {
+		return nil, fmt.Errorf("reading entries of %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("reading entries of %s: %w", name, err)
+	}
+	hashColumn := slices.Index(columns, "hash")
+	if hashColumn < 0 {
+		return nil, &TamperError{File: name, Reason: "missing hash column"}
+	}
+
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range dest {
+		dest[i] = &values[i]
+	}
+
+	prev = start
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("reading entry of %s: %w", name, err)
+		}
+		id, _ := values[0].(int64)
+		hash, _ := values[hashColumn].([]byte)
+
+		entryColumns, entryValues := side.with(id, columns[1:], values[1:])
+		want, err := rowHash(prev, entryColumns, entryValues)
+		if err != nil {
+			return nil, &TamperError{File: name, ID: id, Reason: err.Error()}
+		}
+		if !bytes.Equal(hash, want) {
+			return nil, &TamperError{File: name, ID: id, Reason: "hash mismatch"}
+		}
+		prev = hash
+	}
+
+	return prev, rows.Err()
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hesusruiz/sqlogger"
+)
+
+const diffUsage = `Usage: sqlog diff -at TIME [flags]
+
+Compares the entries logged before and after a time, like a deploy: the errors
+which are new after it, the messages which are no longer logged, and the number
+of entries of every level, per hour to compare windows of different lengths.
+
+The time is like 14:00, 14:00:05, "2024-05-01 14:00" or 2024-05-01T14:00:00Z, in
+the local time zone unless given. A time of the day later than now is of yesterday.
+
+The levels are named like the handler, with TRACE and FATAL for the additional
+levels of sqlogger, and -level-names for the custom ones, like NOTICE=2,AUDIT=12.
+
+Flags:
+`
+
+// The layouts accepted for the times, the first ones without the date.
+var timeLayouts = []string{"15:04", time.TimeOnly, "2006-01-02 15:04", time.DateTime, time.RFC3339Nano}
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), diffUsage)
+		fs.PrintDefaults()
+	}
+	dir := fs.String("dir", ".", "directory of the log files")
+	at := fs.String("at", "", "the time splitting the windows")
+	before := fs.Duration("before", time.Hour, "the length of the window before the time, or from the first entry if 0")
+	after := fs.Duration("after", 0, "the length of the window after the time, or until now if 0")
+	n := fs.Int("n", 20, "the maximum number of fingerprints listed in every section, or all of them if 0")
+	names := levelNames{}
+	fs.Var(names, "level-names", "the names of custom levels, like NOTICE=2,AUDIT=12")
+	fs.Parse(args)
+
+	if *at == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	now := time.Now()
+	split, err := parseTime(*at, now)
+	if err != nil {
+		return err
+	}
+
+	a := sqlogger.Window{Until: split}
+	if *before > 0 {
+		a.Since = split.Add(-*before)
+	}
+	b := sqlogger.Window{Since: split, Until: now}
+	if *after > 0 {
+		b.Until = split.Add(*after)
+	}
+
+	rd, err := sqlogger.OpenSet(*dir)
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+
+	// Without a start, window A begins with the first entry
+	if a.Since.IsZero() {
+		first, err := rd.Query(context.Background(), sqlogger.Query{Until: split, Limit: 1})
+		if err != nil {
+			return err
+		}
+		a.Since = split
+		if len(first) > 0 {
+			a.Since = first[0].Time
+		}
+	}
+
+	diff, err := rd.Compare(context.Background(), a, b)
+	if err != nil {
+		return err
+	}
+	printComparison(os.Stdout, diff, *n, names)
+	return nil
+}
+
+// levelNames are the names of the custom levels given with -level-names, as in
+// Options.LevelNames of the handler.
+type levelNames map[slog.Level]string
+
+func (names levelNames) String() string {
+	var pairs []string
+	for level, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", name, level))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set parses pairs like NOTICE=2, with the level as a number or like INFO+2.
+func (names levelNames) Set(s string) error {
+	for pair := range strings.SplitSeq(s, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("invalid level name %q", pair)
+		}
+		var level slog.Level
+		if n, err := strconv.Atoi(value); err == nil {
+			level = slog.Level(n)
+		} else if err := level.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("invalid level %q", value)
+		}
+		names[level] = name
+	}
+	return nil
+}
+
+// name returns the name of a level, custom or as in the TUI.
+func (names levelNames) name(l slog.Level) string {
+	if name, ok := names[l]; ok {
+		return name
+	}
+	return levelName(l)
+}
+
+// parseTime parses a time in one of timeLayouts. The times without a date are of
+// the last day they happened by now.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	for i, layout := range timeLayouts {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err != nil {
+			continue
+		}
+		if i < 2 {
+			y, m, d := now.Date()
+			t = time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, time.Local)
+			if t.After(now) {
+				t = t.AddDate(0, 0, -1)
+			}
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+func printComparison(w io.Writer, diff *sqlogger.Comparison, n int, names levelNames) {
+	const layout = "2006-01-02 15:04:05"
+	fmt.Fprintf(w, "Before: %s to %s\n", diff.A.Since.Format(layout), diff.A.Until.Format(layout))
+	fmt.Fprintf(w, "After:  %s to %s\n\n", diff.B.Since.Format(layout), diff.B.Until.Format(layout))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "LEVEL\tBEFORE\tAFTER\tBEFORE/H\tAFTER/H\tCHANGE\t")
+	for _, l := range diff.Levels {
+		rateA, rateB := perHour(l.A, diff.A), perHour(l.B, diff.B)
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%.1f\t%s\t\n", names.name(l.Level), l.A, l.B, rateA, rateB, change(rateA, rateB))
+	}
+	tw.Flush()
+
+	printFingerprints(w, "New errors", diff.NewErrors, n)
+	printFingerprints(w, "Disappeared", diff.Disappeared, n)
+}
+
+func printFingerprints(w io.Writer, title string, counts []sqlogger.FingerprintCount, n int) {
+	fmt.Fprintf(w, "\n%s: %d\n", title, len(counts))
+	if n > 0 && len(counts) > n {
+		counts = counts[:n]
+	}
+	if len(counts) == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COUNT\tFINGERPRINT\tSOURCE\tLAST ENTRY")
+	for _, c := range counts {
+		source := "-"
+		if s := c.Last.Source; s != nil {
+			source = fmt.Sprintf("%s:%d", s.File, s.Line)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", c.Count, c.Fingerprint, source, strings.TrimSpace(c.Last.Content))
+	}
+	tw.Flush()
+}
+
+// perHour returns the number of entries per hour in a window.
+func perHour(count int64, w sqlogger.Window) float64 {
+	d := w.Duration()
+	if d <= 0 {
+		return 0
+	}
+	return float64(count) / d.Hours()
+}
+
+// change returns the relative change from a rate to another.
+func change(from, to float64) string {
+	switch {
+	case from == 0 && to == 0:
+		return "-"
+	case from == 0:
+		return "new"
+	}
+	percent := math.Round((to - from) / from * 100)
+	if percent == 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%+.0f%%", percent)
+}
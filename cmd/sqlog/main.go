@@ -0,0 +1,57 @@
+// Command sqlog reads the log files written by sqlogger.
+//
+// Usage:
+//
+//	sqlog <command> [flags]
+//
+// The commands are:
+//
+//	tui     browse the log files of a directory interactively, following new entries
+//	diff    compare the entries logged before and after a time, like a deploy
+//	restore download the shipped log files of a time range, to read them again
+//
+// Run "sqlog <command> -h" for the flags of a command.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: sqlog <command> [flags]
+
+The commands are:
+
+  tui     browse the log files of a directory interactively, following new entries
+  diff    compare the entries logged before and after a time, like a deploy
+  restore download the shipped log files of a time range, to read them again
+
+Run "sqlog <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "tui":
+		err = runTUI(args)
+	case "diff":
+		err = runDiff(args)
+	case "restore":
+		err = runRestore(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "sqlog: unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sqlog: %v\n", err)
+		os.Exit(1)
+	}
+}
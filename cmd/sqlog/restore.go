@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hesusruiz/sqlogger"
+	"github.com/hesusruiz/sqlogger/ship"
+)
+
+const restoreUsage = `Usage: sqlog restore -bucket BUCKET [flags]
+
+Downloads the log files shipped to S3 or a compatible service with entries in a
+time range, and adds them decompressed to the directory of the log files, where
+sqlog and the readers of the set include them. The restored files are named like
+logs.restored-20240501T120000-3.sqlite, and are never deleted by the logger.
+
+The times are like those of sqlog diff. The credentials are taken from the AWS
+environment, like AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+
+The files of encrypted logs are decrypted with the 32-byte key of the logger,
+hex-encoded in the file given with -key-file or in the SQLOG_KEY environment
+variable. sqlog must be built with SQLCipher, like the logger.
+
+Flags:
+`
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), restoreUsage)
+		fs.PrintDefaults()
+	}
+	dir := fs.String("dir", ".", "directory of the log files")
+	instance := fs.String("instance", "", "the instance of the log files, if any")
+	bucket := fs.String("bucket", "", "the bucket of the shipped files")
+	prefix := fs.String("prefix", "", "the prefix of the object keys, as given to the shipper")
+	endpoint := fs.String("endpoint", "", "the URL of the service, if not AWS S3")
+	region := fs.String("region", "", "the region of the bucket, if not in the AWS environment")
+	pathStyle := fs.Bool("path-style", false, "address the bucket in the path, as needed by MinIO")
+	keyFile := fs.String("key-file", "", "file with the hex-encoded key of encrypted log files, instead of SQLOG_KEY")
+	since := fs.String("since", "", "the start of the time range, or the first file if empty")
+	until := fs.String("until", "", "the end of the time range, or the last file if empty")
+	fs.Parse(args)
+
+	if *bucket == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	now := time.Now()
+	opts := &ship.RestoreOptions{Prefix: *prefix}
+	for _, t := range []struct {
+		text string
+		time *time.Time
+	}{{*since, &opts.Since}, {*until, &opts.Until}} {
+		if t.text == "" {
+			continue
+		}
+		var err error
+		if *t.time, err = parseTime(t.text, now); err != nil {
+			return err
+		}
+	}
+
+	storeOpts := &sqlogger.SQLiteOptions{Dir: *dir, Instance: *instance}
+	key, err := encryptionKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	if key != nil {
+		storeOpts.EncryptionKey = func(string) ([]byte, error) { return key, nil }
+	}
+
+	ctx := context.Background()
+	downloader, err := ship.NewS3(ctx, &ship.S3Config{
+		Bucket:       *bucket,
+		Endpoint:     *endpoint,
+		Region:       *region,
+		UsePathStyle: *pathStyle,
+	})
+	if err != nil {
+		return err
+	}
+
+	store := sqlogger.NewSQLiteStore(storeOpts)
+	restored, err := ship.Restore(ctx, downloader, store, opts)
+	for _, name := range restored {
+		fmt.Println(name)
+	}
+	return err
+}
+
+// encryptionKey returns the key of encrypted log files, hex-encoded in keyFile or
+// else in the SQLOG_KEY environment variable, or nil if there is none.
+func encryptionKey(keyFile string) ([]byte, error) {
+	text := os.Getenv("SQLOG_KEY")
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+	key, err := hex.DecodeString(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("the encryption key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
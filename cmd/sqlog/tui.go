@@ -0,0 +1,485 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/hesusruiz/sqlogger"
+)
+
+const tuiUsage = `Usage: sqlog tui [flags]
+
+Browses the log files of a directory, following the new entries as they are logged.
+
+Keys:
+  up/down, pgup/pgdown, g/G   move
+  enter                       show the details and the attributes of the entry
+  /                           search the content, as it is typed
+  1-5                         show or hide TRACE, DEBUG, INFO, WARN and ERROR entries
+  f                           follow the new entries
+  q                           quit
+
+Flags:
+`
+
+// The interval between the reads of the new entries.
+const tailInterval = time.Second
+
+// The level classes which can be shown or hidden, with keys 1 to 5.
+var levelClasses = []struct {
+	name  string
+	level slog.Level
+	style lipgloss.Style
+}{
+	{"TRACE", sqlogger.LevelTrace, lipgloss.NewStyle().Faint(true)},
+	{"DEBUG", slog.LevelDebug, lipgloss.NewStyle().Faint(true)},
+	{"INFO", slog.LevelInfo, lipgloss.NewStyle()},
+	{"WARN", slog.LevelWarn, lipgloss.NewStyle().Foreground(lipgloss.Color("3"))},
+	{"ERROR", slog.LevelError, lipgloss.NewStyle().Foreground(lipgloss.Color("1"))},
+}
+
+var (
+	barStyle      = lipgloss.NewStyle().Reverse(true)
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	labelStyle    = lipgloss.NewStyle().Bold(true)
+)
+
+func runTUI(args []string) error {
+	fs := flag.NewFlagSet("tui", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), tuiUsage)
+		fs.PrintDefaults()
+	}
+	dir := fs.String("dir", ".", "directory of the log files")
+	since := fs.Duration("since", time.Hour, "read the entries logged in this time until now, or all of them if 0")
+	fs.Parse(args)
+
+	rd, err := sqlogger.OpenSet(*dir)
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+
+	m := newTUIModel(rd, *dir)
+	if *since > 0 {
+		m.last = time.Now().Add(-*since)
+	}
+
+	_, err = tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+// tuiModel is the state of the browser: the entries read so far, the filters, and
+// the entry shown in the detail view, if any.
+type tuiModel struct {
+	rd  *sqlogger.Reader
+	dir string
+
+	// The entries read, oldest first, and the indexes of those shown.
+	entries []sqlogger.Entry
+	visible []int
+
+	// The time of the last entry read, and the number of entries read with that
+	// time, to read only the new ones in the next poll.
+	last     time.Time
+	lastSeen int
+
+	hidden    [5]bool
+	search    textinput.Model
+	searching bool
+	follow    bool
+
+	cursor, offset int
+	width, height  int
+
+	detail     viewport.Model
+	showDetail bool
+
+	err error
+}
+
+type entriesMsg struct {
+	entries []sqlogger.Entry
+	err     error
+}
+
+type tickMsg time.Time
+
+type detailMsg struct {
+	entry sqlogger.Entry
+	attrs []slog.Attr
+	err   error
+}
+
+func newTUIModel(rd *sqlogger.Reader, dir string) *tuiModel {
+	search := textinput.New()
+	search.Prompt = "/"
+	return &tuiModel{rd: rd, dir: dir, search: search, follow: true}
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return m.poll()
+}
+
+// poll reads the entries logged since the last ones read.
+func (m *tuiModel) poll() tea.Cmd {
+	rd, q := m.rd, sqlogger.Query{Since: m.last}
+	return func() tea.Msg {
+		if err := rd.Refresh(); err != nil {
+			return entriesMsg{err: err}
+		}
+		entries, err := rd.Query(context.Background(), q)
+		return entriesMsg{entries: entries, err: err}
+	}
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(tailInterval, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.detail.Width, m.detail.Height = msg.Width, msg.Height-2
+		m.scroll()
+		return m, nil
+
+	case entriesMsg:
+		m.err = msg.err
+		m.add(msg.entries)
+		return m, tick()
+
+	case tickMsg:
+		return m, m.poll()
+
+	case detailMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.detail = viewport.New(m.width, m.height-2)
+		m.detail.SetContent(m.renderDetail(msg.entry, msg.attrs))
+		m.showDetail = true
+		return m, nil
+
+	case tea.KeyMsg:
+		switch {
+		case msg.String() == "ctrl+c":
+			return m, tea.Quit
+		case m.showDetail:
+			return m.updateDetail(msg)
+		case m.searching:
+			return m.updateSearch(msg)
+		default:
+			return m.updateList(msg)
+		}
+	}
+
+	return m, nil
+}
+
+func (m *tuiModel) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	page := m.listHeight()
+
+	switch key := msg.String(); key {
+	case "q":
+		return m, tea.Quit
+	case "up", "k":
+		m.move(-1)
+	case "down", "j":
+		m.move(1)
+	case "pgup", "ctrl+b":
+		m.move(-page)
+	case "pgdown", "ctrl+f", " ":
+		m.move(page)
+	case "home", "g":
+		m.move(-len(m.visible))
+	case "end", "G":
+		m.move(len(m.visible))
+	case "f":
+		m.follow = !m.follow
+		if m.follow {
+			m.move(len(m.visible))
+		}
+	case "/":
+		m.searching = true
+		return m, m.search.Focus()
+	case "esc":
+		m.search.SetValue("")
+		m.filter()
+	case "enter":
+		if len(m.visible) == 0 {
+			return m, nil
+		}
+		rd, e := m.rd, m.entries[m.visible[m.cursor]]
+		return m, func() tea.Msg {
+			attrs, err := rd.Attrs(context.Background(), e)
+			return detailMsg{entry: e, attrs: attrs, err: err}
+		}
+	case "1", "2", "3", "4", "5":
+		i := int(key[0] - '1')
+		m.hidden[i] = !m.hidden[i]
+		m.filter()
+	}
+
+	return m, nil
+}
+
+func (m *tuiModel) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.searching = false
+		m.search.Blur()
+		return m, nil
+	case "esc":
+		m.searching = false
+		m.search.Blur()
+		m.search.SetValue("")
+		m.filter()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	m.filter()
+	return m, cmd
+}
+
+func (m *tuiModel) updateDetail(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q", "enter":
+		m.showDetail = false
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.detail, cmd = m.detail.Update(msg)
+	return m, cmd
+}
+
+// add appends the new entries, skipping those already read.
+func (m *tuiModel) add(entries []sqlogger.Entry) {
+	skipped := 0
+	for skipped < m.lastSeen && skipped < len(entries) && entries[skipped].Time.Equal(m.last) {
+		skipped++
+	}
+	entries = entries[skipped:]
+
+	for _, e := range entries {
+		if e.Time.Equal(m.last) {
+			m.lastSeen++
+		} else {
+			m.last, m.lastSeen = e.Time, 1
+		}
+		m.entries = append(m.entries, e)
+		if m.shown(e) {
+			m.visible = append(m.visible, len(m.entries)-1)
+		}
+	}
+
+	if m.follow {
+		m.move(len(m.visible))
+	}
+}
+
+// filter recomputes the entries shown after a change of the filters, keeping the
+// selected entry if it is still shown.
+func (m *tuiModel) filter() {
+	selected := -1
+	if m.cursor < len(m.visible) {
+		selected = m.visible[m.cursor]
+	}
+
+	m.visible = m.visible[:0]
+	m.cursor = 0
+	for i, e := range m.entries {
+		if m.shown(e) {
+			if i <= selected {
+				m.cursor = len(m.visible)
+			}
+			m.visible = append(m.visible, i)
+		}
+	}
+
+	if m.follow {
+		m.move(len(m.visible))
+	}
+	m.scroll()
+}
+
+func (m *tuiModel) shown(e sqlogger.Entry) bool {
+	if m.hidden[levelClass(e.Level)] {
+		return false
+	}
+	search := strings.ToLower(m.search.Value())
+	return search == "" || strings.Contains(strings.ToLower(e.Content), search)
+}
+
+// levelClass returns the index in levelClasses of the class of a level.
+func levelClass(l slog.Level) int {
+	for i := len(levelClasses) - 1; i > 0; i-- {
+		if l >= levelClasses[i].level {
+			return i
+		}
+	}
+	return 0
+}
+
+// move moves the cursor by n entries. Moving up stops following the new entries.
+func (m *tuiModel) move(n int) {
+	m.cursor = max(0, min(m.cursor+n, len(m.visible)-1))
+	if n < 0 {
+		m.follow = false
+	}
+	m.scroll()
+}
+
+// scroll keeps the cursor inside the visible part of the list.
+func (m *tuiModel) scroll() {
+	h := m.listHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+h {
+		m.offset = m.cursor - h + 1
+	}
+	m.offset = max(0, min(m.offset, len(m.visible)-h))
+}
+
+func (m *tuiModel) listHeight() int {
+	// The header and the footer take a line each
+	return max(1, m.height-2)
+}
+
+func (m *tuiModel) View() string {
+	if m.width == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(m.header())
+	b.WriteByte('\n')
+
+	if m.showDetail {
+		b.WriteString(m.detail.View())
+		b.WriteByte('\n')
+		b.WriteString(m.footer("up/down scroll · esc back"))
+		return b.String()
+	}
+
+	h := m.listHeight()
+	for row := range h {
+		i := m.offset + row
+		if i < len(m.visible) {
+			e := m.entries[m.visible[i]]
+			line, _, _ := strings.Cut(strings.TrimSpace(e.Content), "\n")
+			line = truncate(line, m.width)
+			style := levelClasses[levelClass(e.Level)].style
+			if i == m.cursor {
+				style = selectedStyle
+				line += strings.Repeat(" ", max(0, m.width-lipgloss.Width(line)))
+			}
+			b.WriteString(style.Render(line))
+		}
+		b.WriteByte('\n')
+	}
+
+	switch {
+	case m.searching || m.search.Value() != "":
+		b.WriteString(m.search.View())
+	case m.err != nil:
+		b.WriteString(m.footer("error: " + m.err.Error()))
+	default:
+		b.WriteString(m.footer("enter details · / search · 1-5 levels · f follow · q quit"))
+	}
+
+	return b.String()
+}
+
+// header shows the directory, the number of entries and the state of the filters.
+func (m *tuiModel) header() string {
+	var levels []string
+	for i, c := range levelClasses {
+		if !m.hidden[i] {
+			levels = append(levels, c.name)
+		}
+	}
+	follow := ""
+	if m.follow {
+		follow = " · following"
+	}
+	text := fmt.Sprintf(" %s · %d/%d entries · %s%s", m.dir, len(m.visible), len(m.entries), strings.Join(levels, " "), follow)
+	return barStyle.Render(truncate(text, m.width) + strings.Repeat(" ", max(0, m.width-lipgloss.Width(text))))
+}
+
+func (m *tuiModel) footer(text string) string {
+	return lipgloss.NewStyle().Faint(true).Render(truncate(" "+text, m.width))
+}
+
+// renderDetail renders all the fields of an entry and its attributes.
+func (m *tuiModel) renderDetail(e sqlogger.Entry, attrs []slog.Attr) string {
+	var b strings.Builder
+	field := func(label, value string) {
+		fmt.Fprintf(&b, "%s %s\n", labelStyle.Render(fmt.Sprintf("%-10s", label)), value)
+	}
+
+	field("Time", e.Time.Format(time.RFC3339Nano))
+	field("Level", levelName(e.Level))
+	field("File", fmt.Sprintf("%s (entry %d)", e.File, e.ID))
+	if e.Source != nil {
+		field("Source", fmt.Sprintf("%s:%d %s", e.Source.File, e.Source.Line, e.Source.Function))
+	}
+	if e.RepeatCount > 1 {
+		field("Repeated", fmt.Sprintf("%d times, last at %s", e.RepeatCount, e.LastTime.Format(time.RFC3339Nano)))
+	}
+
+	b.WriteString("\n" + labelStyle.Render("Content") + "\n")
+	b.WriteString(lipgloss.NewStyle().Width(max(1, m.width-2)).PaddingLeft(2).Render(strings.TrimSpace(e.Content)))
+	b.WriteString("\n")
+
+	b.WriteString("\n" + labelStyle.Render("Attributes") + "\n")
+	if len(attrs) == 0 {
+		b.WriteString("  none stored (see Options.AttrTable)\n")
+	}
+	for _, a := range attrs {
+		if v, ok := a.Value.Any().([]byte); ok {
+			fmt.Fprintf(&b, "  %s = %x (%d bytes)\n", a.Key, v, len(v))
+			continue
+		}
+		fmt.Fprintf(&b, "  %s = %s\n", a.Key, a.Value)
+	}
+
+	if len(e.Stack) > 0 {
+		b.WriteString("\n" + labelStyle.Render("Stack") + "\n")
+		for _, frame := range e.Stack {
+			fmt.Fprintf(&b, "  %s\n      %s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+	}
+
+	return b.String()
+}
+
+func levelName(l slog.Level) string {
+	switch l {
+	case sqlogger.LevelTrace:
+		return "TRACE"
+	case sqlogger.LevelFatal:
+		return "FATAL"
+	}
+	return l.String()
+}
+
+// truncate cuts a line to the width of the terminal.
+func truncate(s string, width int) string {
+	return ansi.Truncate(s, width, "")
+}
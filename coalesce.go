@@ -0,0 +1,53 @@
+package sqlogger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// coalescer tracks the last record stored, to collapse consecutive repeats of the
+// same record into a single entry with a repeat count.
+type coalescer struct {
+	mu    sync.Mutex
+	key   string
+	unit  string
+	count int64
+	last  time.Time
+}
+
+// coalesceKey identifies the records which are considered repeats of each other.
+func coalesceKey(level slog.Level, msg string, attrs []slog.Attr) string {
+	return level.String() + "\x00" + msg + "\x00" + attrsText(attrs)
+}
+
+// repeat reports whether the record with the given key repeats the last one stored
+// in the storage unit, updating the count if so. Otherwise the record starts a new
+// run, and the notice of the previous run is written to w if it had repeats.
+// It must be called with the lock held.
+func (c *coalescer) repeat(key string, unit string, t time.Time, w io.Writer) bool {
+	if key == c.key && unit == c.unit {
+		c.count++
+		c.last = t
+		return true
+	}
+
+	c.flushNotice(w)
+	c.key = key
+	c.unit = unit
+	c.count = 1
+	c.last = t
+	return false
+}
+
+// flushNotice writes a line telling how many times the last record was repeated,
+// if it was. It must be called with the lock held.
+func (c *coalescer) flushNotice(w io.Writer) {
+	if c.count > 1 {
+		fmt.Fprintf(w, "%s last message repeated %d times\n", c.last.Format(time.TimeOnly), c.count)
+	}
+	c.count = 0
+	c.key = ""
+}
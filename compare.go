@@ -0,0 +1,164 @@
+package sqlogger
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Window is a time range of the entries, from Since included to Until excluded.
+// A zero time means no limit on that side.
+type Window struct {
+	Since time.Time
+	Until time.Time
+}
+
+// Duration returns the length of the window, or zero if it is not limited on
+// both sides.
+func (w Window) Duration() time.Duration {
+	if w.Since.IsZero() || w.Until.IsZero() {
+		return 0
+	}
+	return w.Until.Sub(w.Since)
+}
+
+// Comparison is what changed in the entries from a window A to a window B.
+type Comparison struct {
+	A, B Window
+
+	// NewErrors are the fingerprints of the entries of level ERROR or above in B
+	// which were not errors in A, the most frequent first, with their count in B.
+	NewErrors []FingerprintCount
+
+	// Disappeared are the fingerprints of the entries of any level in A which
+	// were not logged in B, the most frequent first, with their count in A.
+	Disappeared []FingerprintCount
+
+	// Levels are the number of entries of every level logged in either window,
+	// ordered by level.
+	Levels []LevelVolume
+}
+
+// LevelVolume is the number of entries of a level in the two windows of a
+// Comparison, including the repetitions of coalesced entries.
+type LevelVolume struct {
+	Level slog.Level
+	A, B  int64
+}
+
+// Compare reports what changed from window a to window b, like what started
+// failing after a deploy:
+//
+//	diff, err := rd.Compare(ctx,
+//		sqlogger.Window{Since: deploy.Add(-time.Hour), Until: deploy},
+//		sqlogger.Window{Since: deploy, Until: time.Now()})
+//
+// The entries are compared by fingerprint, so the messages with different counts
+// or ids are the same. Files written by versions without fingerprints are skipped
+// for NewErrors and Disappeared, but counted in Levels.
+func (rd *Reader) Compare(ctx context.Context, a, b Window) (*Comparison, error) {
+	for _, w := range []Window{a, b} {
+		if !w.Since.IsZero() && !w.Until.IsZero() && w.Until.Before(w.Since) {
+			return nil, fmt.Errorf("window ends before it starts")
+		}
+	}
+
+	rd.mu.RLock()
+	defer rd.mu.RUnlock()
+
+	qa := Query{Since: a.Since, Until: a.Until}
+	qb := Query{Since: b.Since, Until: b.Until}
+
+	c := &Comparison{A: a, B: b}
+
+	// The errors of B which were not errors in A
+	errorsA, err := rd.fingerprintCounts(ctx, Query{Since: a.Since, Until: a.Until, MinLevel: slog.LevelError})
+	if err != nil {
+		return nil, err
+	}
+	errorsB, err := rd.fingerprintCounts(ctx, Query{Since: b.Since, Until: b.Until, MinLevel: slog.LevelError})
+	if err != nil {
+		return nil, err
+	}
+	maps.DeleteFunc(errorsB, func(fp string, _ *FingerprintCount) bool {
+		return errorsA[fp] != nil
+	})
+	c.NewErrors = sortFingerprints(errorsB, 0)
+
+	// The entries of A not logged in B
+	allA, err := rd.fingerprintCounts(ctx, qa)
+	if err != nil {
+		return nil, err
+	}
+	allB, err := rd.fingerprintCounts(ctx, qb)
+	if err != nil {
+		return nil, err
+	}
+	maps.DeleteFunc(allA, func(fp string, _ *FingerprintCount) bool {
+		return allB[fp] != nil
+	})
+	c.Disappeared = sortFingerprints(allA, 0)
+
+	levelsA, err := rd.levelCounts(ctx, qa)
+	if err != nil {
+		return nil, err
+	}
+	levelsB, err := rd.levelCounts(ctx, qb)
+	if err != nil {
+		return nil, err
+	}
+	for level := range levelsA {
+		c.Levels = append(c.Levels, LevelVolume{Level: level, A: levelsA[level], B: levelsB[level]})
+	}
+	for level, n := range levelsB {
+		if _, ok := levelsA[level]; !ok {
+			c.Levels = append(c.Levels, LevelVolume{Level: level, B: n})
+		}
+	}
+	slices.SortFunc(c.Levels, func(x, y LevelVolume) int {
+		return cmp.Compare(x.Level, y.Level)
+	})
+
+	return c, nil
+}
+
+// levelCounts counts the entries selected by q by level, with the read lock held.
+func (rd *Reader) levelCounts(ctx context.Context, q Query) (map[slog.Level]int64, error) {
+	where, args := queryWhere(q, rd.indexedColumns)
+
+	counts := map[slog.Level]int64{}
+	for _, f := range rd.files {
+		// The files without repetition counts have one entry per record
+		count := "COUNT(*)"
+		ok, err := f.hasColumn(ctx, "repeat_count")
+		if err != nil {
+			return nil, fmt.Errorf("%s: counting log entries: %w", f.name, err)
+		}
+		if ok {
+			count = "SUM(repeat_count)"
+		}
+
+		rows, err := f.db.QueryContext(ctx, "SELECT level, "+count+" FROM entries"+where+" GROUP BY level", args...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: counting log entries: %w", f.name, err)
+		}
+		for rows.Next() {
+			var level int
+			var count int64
+			if err := rows.Scan(&level, &count); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("%s: counting log entries: %w", f.name, err)
+			}
+			counts[slog.Level(level)] += count
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("%s: counting log entries: %w", f.name, err)
+		}
+	}
+	return counts, nil
+}
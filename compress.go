@@ -0,0 +1,164 @@
+package sqlogger
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressedExtension is appended to the name of a database file when it is compressed.
+const compressedExtension = ".zst"
+
+// compressDB compacts the database into a new file with VACUUM INTO, closes it,
+// and replaces the database file by its zstd-compressed compacted copy.
+func compressDB(db *sql.DB, name string) error {
+	compact := name + ".compact"
+	os.Remove(compact)
+
+	if _, err := db.Exec("VACUUM INTO ?", compact); err != nil {
+		closeDB(db, false)
+		return fmt.Errorf("compacting %s: %w", name, err)
+	}
+
+	if err := closeDB(db, false); err != nil {
+		os.Remove(compact)
+		return err
+	}
+
+	err := compressFile(compact, name+compressedExtension)
+	os.Remove(compact)
+	if err != nil {
+		return fmt.Errorf("compressing %s: %w", name, err)
+	}
+
+	// The compressed file is the only copy from now on
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Remove(name + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compressFile writes the zstd compression of src to dst, atomically.
+func compressFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	enc, err := zstd.NewWriter(out)
+	if err == nil {
+		_, err = io.Copy(enc, in)
+		err = errors.Join(err, enc.Close())
+	}
+	err = errors.Join(err, out.Sync(), out.Close())
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, dst)
+}
+
+// decompressFile writes the decompression of the zstd file src to a new
+// temporary file, returning its name.
+func decompressFile(src string) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	dec, err := zstd.NewReader(in)
+	if err != nil {
+		return "", err
+	}
+	defer dec.Close()
+
+	out, err := os.CreateTemp("", "sqlogger-*.sqlite")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.Copy(out, dec)
+	err = errors.Join(err, out.Close())
+	if err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("decompressing %s: %w", src, err)
+	}
+
+	return out.Name(), nil
+}
+
+// OpenLogFile opens a log database file for reading. Compressed files are
+// decompressed transparently into a temporary file.
+// The returned function closes the database and removes any temporary file.
+func OpenLogFile(name string) (*sql.DB, func() error, error) {
+	return NewSQLiteStore(nil).openLogFile(name)
+}
+
+func (s *SQLiteStore) openLogFile(name string) (*sql.DB, func() error, error) {
+	if isRestored(name) {
+		return openRestored(name)
+	}
+	if !strings.HasSuffix(name, compressedExtension) {
+		db, err := s.openDB(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, db.Close, nil
+	}
+
+	tmp, err := decompressFile(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The key of a compressed file is the key of the original file
+	db, err := s.openKeyedDB(tmp, strings.TrimSuffix(name, compressedExtension))
+	if err != nil {
+		os.Remove(tmp)
+		return nil, nil, err
+	}
+
+	cleanup := func() error {
+		return errors.Join(db.Close(), os.Remove(tmp))
+	}
+
+	return db, cleanup, nil
+}
+
+// ringFile returns the path of the database file with the given number in the ring,
+// either plain or compressed, or the empty string if it does not exist.
+func (s *SQLiteStore) ringFile(logId int) string {
+	name := s.fileName(logId)
+	if _, err := os.Stat(name); err == nil {
+		return name
+	}
+	if _, err := os.Stat(name + compressedExtension); err == nil {
+		return name + compressedExtension
+	}
+	return ""
+}
+
+// sealedName returns the final name of a file after it has been sealed by rotation.
+func (s *SQLiteStore) sealedName(name string) string {
+	if s.compressRotated {
+		return name + compressedExtension
+	}
+	return name
+}
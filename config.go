@@ -0,0 +1,301 @@
+package sqlogger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables read by OptionsFromEnv.
+const EnvPrefix = "SQLOGGER_"
+
+// config holds the options which can be set from a file or the environment, with
+// the names of the keys, like "level" or "rotation_naming". The environment
+// variables are the keys in uppercase with EnvPrefix, like SQLOGGER_LEVEL.
+type config struct {
+	Level              string            `yaml:"level"`
+	LevelRules         map[string]string `yaml:"level_rules"`
+	SyncOnLevel        string            `yaml:"sync_on_level"`
+	Service            string            `yaml:"service"`
+	Dir                string            `yaml:"dir"`
+	RotationNaming     string            `yaml:"rotation_naming"`
+	Instance           string            `yaml:"instance"`
+	Color              string            `yaml:"color"`
+	NoSource           bool              `yaml:"no_source"`
+	ExpandErrors       bool              `yaml:"expand_errors"`
+	Audit              bool              `yaml:"audit"`
+	AppendOnly         bool              `yaml:"append_only"`
+	VacuumOnClose      bool              `yaml:"vacuum_on_close"`
+	CompressRotated    bool              `yaml:"compress_rotated"`
+	AttrTable          bool              `yaml:"attr_table"`
+	AttrKeys           []string          `yaml:"attr_keys"`
+	IndexedAttrs       []string          `yaml:"indexed_attrs"`
+	BlobTable          bool              `yaml:"blob_table"`
+	MaxMessageBytes    int               `yaml:"max_message_bytes"`
+	MaxAttrBytes       int               `yaml:"max_attr_bytes"`
+	MaxBinaryAttrBytes int               `yaml:"max_binary_attr_bytes"`
+	AsyncConsole       bool              `yaml:"async_console"`
+	ConsoleQueueSize   int               `yaml:"console_queue_size"`
+	CoalesceRepeats    bool              `yaml:"coalesce_repeats"`
+	RetryQueueSize     int               `yaml:"retry_queue_size"`
+	MaxEntries         int               `yaml:"max_entries"`
+	NumLogFiles        int               `yaml:"num_log_files"`
+	CopyNamedToMain    bool              `yaml:"copy_named_to_main"`
+	WriteTimeout       string            `yaml:"write_timeout"`
+	WALCheckpointEvery string            `yaml:"wal_checkpoint_every"`
+	WALSizeLimit       int64             `yaml:"wal_size_limit"`
+}
+
+// OptionsFromFile reads the options from a YAML or JSON file, with keys like
+// "level", "dir", "rotation_naming" or "color":
+//
+//	level: debug
+//	dir: /var/log/orders
+//	rotation_naming: timestamp
+//	num_log_files: 14
+//	level_rules:
+//	  http: warn
+//	  component=db: debug
+//
+// Levels are names like "info" or "warn+2", including "trace" and "fatal".
+// Durations are like "30s". Unknown keys and invalid values are reported with the
+// name of the file and the key.
+func OptionsFromFile(path string) (*Options, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return c.options(func(key string) string { return path + ": " + key })
+}
+
+// OptionsFromEnv reads the options from the environment variables named as the
+// keys of OptionsFromFile in uppercase with the prefix SQLOGGER_, like
+// SQLOGGER_LEVEL=debug or SQLOGGER_ROTATION_NAMING=timestamp. Lists are separated
+// by commas, like SQLOGGER_INDEXED_ATTRS=request_id,tenant, and so are the level
+// rules, like SQLOGGER_LEVEL_RULES=http=warn,component=db=debug.
+// Options without a variable keep their zero value.
+func OptionsFromEnv() (*Options, error) {
+	var c config
+
+	v := reflect.ValueOf(&c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key := v.Type().Field(i).Tag.Get("yaml")
+		name := EnvPrefix + strings.ToUpper(key)
+		text, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setEnvField(v.Field(i), strings.TrimSpace(text)); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return c.options(func(key string) string { return EnvPrefix + strings.ToUpper(key) })
+}
+
+// setEnvField sets a field of config from the text of an environment variable.
+func setEnvField(f reflect.Value, text string) error {
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", text)
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", text)
+		}
+		f.SetInt(n)
+	case reflect.Slice:
+		f.Set(reflect.ValueOf(splitList(text)))
+	case reflect.Map:
+		m := map[string]string{}
+		for _, item := range splitList(text) {
+			// The keys of the level rules can contain '=' themselves
+			i := strings.LastIndexByte(item, '=')
+			if i <= 0 {
+				return fmt.Errorf("invalid item %q, must be key=value", item)
+			}
+			m[item[:i]] = item[i+1:]
+		}
+		f.Set(reflect.ValueOf(m))
+	}
+	return nil
+}
+
+func splitList(text string) []string {
+	var items []string
+	for _, item := range strings.Split(text, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// options validates the config and converts it to Options, naming the keys in the
+// errors with name.
+func (c *config) options(name func(key string) string) (*Options, error) {
+	opts := &Options{
+		Service:            c.Service,
+		Dir:                c.Dir,
+		Instance:           c.Instance,
+		NoSource:           c.NoSource,
+		ExpandErrors:       c.ExpandErrors,
+		Audit:              c.Audit,
+		AppendOnly:         c.AppendOnly,
+		VacuumOnClose:      c.VacuumOnClose,
+		CompressRotated:    c.CompressRotated,
+		AttrTable:          c.AttrTable,
+		AttrKeys:           c.AttrKeys,
+		IndexedAttrs:       c.IndexedAttrs,
+		BlobTable:          c.BlobTable,
+		MaxMessageBytes:    c.MaxMessageBytes,
+		MaxAttrBytes:       c.MaxAttrBytes,
+		MaxBinaryAttrBytes: c.MaxBinaryAttrBytes,
+		AsyncConsole:       c.AsyncConsole,
+		ConsoleQueueSize:   c.ConsoleQueueSize,
+		CoalesceRepeats:    c.CoalesceRepeats,
+		RetryQueueSize:     c.RetryQueueSize,
+		MaxEntries:         c.MaxEntries,
+		NumLogFiles:        c.NumLogFiles,
+		CopyNamedToMain:    c.CopyNamedToMain,
+		WALSizeLimit:       c.WALSizeLimit,
+	}
+
+	var errs []error
+	fail := func(key string, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", name(key), fmt.Sprintf(format, args...)))
+	}
+
+	if c.Level != "" {
+		if l, err := parseLevel(c.Level); err != nil {
+			fail("level", "%v", err)
+		} else {
+			opts.Level = l
+		}
+	}
+	if c.SyncOnLevel != "" {
+		if l, err := parseLevel(c.SyncOnLevel); err != nil {
+			fail("sync_on_level", "%v", err)
+		} else {
+			opts.SyncOnLevel = l
+		}
+	}
+	if len(c.LevelRules) > 0 {
+		opts.LevelRules = map[string]slog.Leveler{}
+		for _, key := range slices.Sorted(maps.Keys(c.LevelRules)) {
+			l, err := parseLevel(c.LevelRules[key])
+			switch {
+			case !validLevelRuleKey(key):
+				fail("level_rules", "invalid rule %q", key)
+			case err != nil:
+				fail("level_rules", "rule %q: %v", key, err)
+			default:
+				opts.LevelRules[key] = l
+			}
+		}
+	}
+
+	switch strings.ToLower(c.RotationNaming) {
+	case "", "number":
+		opts.RotationNaming = ByNumber
+	case "timestamp":
+		opts.RotationNaming = ByTimestamp
+	default:
+		fail("rotation_naming", "unknown naming %q, must be number or timestamp", c.RotationNaming)
+	}
+
+	switch strings.ToLower(c.Color) {
+	case "", "auto":
+		opts.Color = ColorAuto
+	case "always":
+		opts.Color = ColorAlways
+	case "never":
+		opts.Color = ColorNever
+	default:
+		fail("color", "unknown color mode %q, must be auto, always or never", c.Color)
+	}
+
+	if c.Instance != "" && !validInstance.MatchString(c.Instance) {
+		fail("instance", "invalid instance name %q", c.Instance)
+	}
+	if c.WALCheckpointEvery != "" {
+		if d, err := time.ParseDuration(c.WALCheckpointEvery); err != nil || d < 0 {
+			fail("wal_checkpoint_every", "invalid duration %q", c.WALCheckpointEvery)
+		} else {
+			opts.WALCheckpointEvery = d
+		}
+	}
+	if c.WriteTimeout != "" {
+		if d, err := time.ParseDuration(c.WriteTimeout); err != nil || d < 0 {
+			fail("write_timeout", "invalid duration %q", c.WriteTimeout)
+		} else {
+			opts.WriteTimeout = d
+		}
+	}
+	if c.MaxEntries < 0 {
+		fail("max_entries", "must not be negative")
+	}
+	if c.NumLogFiles < 0 {
+		fail("num_log_files", "must not be negative")
+	}
+	if c.MaxMessageBytes < 0 {
+		fail("max_message_bytes", "must not be negative")
+	}
+	if c.MaxAttrBytes < 0 {
+		fail("max_attr_bytes", "must not be negative")
+	}
+	if c.MaxBinaryAttrBytes < 0 {
+		fail("max_binary_attr_bytes", "must not be negative")
+	}
+	if c.ConsoleQueueSize < 0 {
+		fail("console_queue_size", "must not be negative")
+	}
+	if c.WALSizeLimit < 0 {
+		fail("wal_size_limit", "must not be negative")
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// parseLevel parses the name of a level, like "info", "WARN+2", "trace" or "fatal".
+func parseLevel(text string) (slog.Level, error) {
+	switch strings.ToUpper(text) {
+	case "TRACE":
+		return LevelTrace, nil
+	case "FATAL":
+		return LevelFatal, nil
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(text)); err != nil {
+		return 0, fmt.Errorf("unknown level %q", text)
+	}
+	return l, nil
+}
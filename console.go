@@ -0,0 +1,178 @@
+package sqlogger
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-colorable"
+	"github.com/mattn/go-isatty"
+)
+
+// ColorMode controls the colors of the console output.
+type ColorMode int
+
+const (
+	// ColorAuto colors the output only when stdout is a terminal, honoring the
+	// NO_COLOR and FORCE_COLOR environment variables.
+	ColorAuto ColorMode = iota
+
+	// ColorAlways colors the output even when it is piped or redirected.
+	ColorAlways
+
+	// ColorNever disables the colors.
+	ColorNever
+)
+
+// useColor resolves the color mode for the given output file.
+func useColor(mode ColorMode, f *os.File) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	// See https://no-color.org and https://force-color.org
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if force := os.Getenv("FORCE_COLOR"); force != "" && force != "0" {
+		return true
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	fd := f.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// consoleWriter returns the writer for the console output, which for colored output
+// to a file on Windows translates the escape sequences for the consoles which do
+// not support them.
+func consoleWriter(w io.Writer, colored bool) io.Writer {
+	if f, ok := w.(*os.File); ok && colored {
+		return colorable.NewColorable(f)
+	}
+	return w
+}
+
+// Theme holds the colors of the console output.
+type Theme struct {
+	// Time is the color of the timestamp.
+	Time *color.Color
+
+	// Key is the color of the attribute keys.
+	Key *color.Color
+
+	// Source is the color of the location of the log call.
+	Source *color.Color
+
+	// Levels are the colors of the level names. Levels not in the map are
+	// printed without color.
+	Levels map[slog.Level]*color.Color
+}
+
+// DefaultTheme returns the default colors: grey time and keys, blue source, and
+// magenta, green, yellow and red for DEBUG, INFO, WARN and ERROR, with dark grey
+// for LevelTrace and bold red for LevelFatal.
+func DefaultTheme() *Theme {
+	const glevel = 130
+	return &Theme{
+		Time:   color.RGB(glevel, glevel, glevel),
+		Key:    color.RGB(glevel, glevel, glevel),
+		Source: color.New(color.FgBlue),
+		Levels: map[slog.Level]*color.Color{
+			slog.LevelDebug: color.New(color.FgMagenta),
+			slog.LevelInfo:  color.New(color.FgGreen),
+			slog.LevelWarn:  color.New(color.FgYellow),
+			slog.LevelError: color.New(color.FgRed),
+			LevelTrace:      color.New(color.FgHiBlack),
+			LevelFatal:      color.New(color.FgHiRed, color.Bold),
+		},
+	}
+}
+
+// Layout configures the format of the console output. The zero value is the
+// default layout. The content stored in the database is not affected.
+type Layout struct {
+	// TimeFormat is the layout of the timestamp, like time.RFC3339 or
+	// time.DateTime. If empty, time.TimeOnly is used.
+	TimeFormat string
+
+	// HideTime omits the timestamp.
+	HideTime bool
+
+	// HideSource omits the location of the log call, which is still stored
+	// unless Options.NoSource is set.
+	HideSource bool
+
+	// NoLevelPadding disables the padding of the level names to 5 characters.
+	NoLevelPadding bool
+}
+
+// escapes are the ANSI sequences around a colored text, empty if the color is
+// disabled.
+type escapes struct {
+	start, end string
+}
+
+func newEscapes(c *color.Color) escapes {
+	start, end, _ := strings.Cut(c.Sprint("\x00"), "\x00")
+	return escapes{start, end}
+}
+
+// wrap appends s in the color.
+func (e escapes) wrap(buf []byte, s string) []byte {
+	buf = append(buf, e.start...)
+	buf = append(buf, s...)
+	return append(buf, e.end...)
+}
+
+// palette holds the escape sequences of the colors of a theme, computed once so
+// the lines are rendered without formatting.
+type palette struct {
+	time, key, source escapes
+	levels            map[slog.Level]escapes
+}
+
+// newPalette returns the escape sequences of the theme with its colors enabled
+// or disabled, independent of the global color.NoColor setting. Missing colors
+// are taken from the default theme.
+func newPalette(t *Theme, enabled bool) *palette {
+	def := DefaultTheme()
+	if t == nil {
+		t = def
+	}
+
+	setColor := func(c *color.Color, fallback *color.Color) escapes {
+		if c == nil {
+			c = fallback
+		}
+		// Copy the color, so the one of the caller is not modified
+		cc := *c
+		if enabled {
+			cc.EnableColor()
+		} else {
+			cc.DisableColor()
+		}
+		return newEscapes(&cc)
+	}
+
+	p := &palette{
+		time:   setColor(t.Time, def.Time),
+		key:    setColor(t.Key, def.Key),
+		source: setColor(t.Source, def.Source),
+		levels: map[slog.Level]escapes{},
+	}
+	for level, c := range t.Levels {
+		if c != nil {
+			p.levels[level] = setColor(c, nil)
+		}
+	}
+
+	return p
+}
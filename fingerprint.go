@@ -0,0 +1,157 @@
+package sqlogger
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// fingerprint returns the fingerprint of the entries logged from function with
+// the message msg. The line is left out, so it survives the edits of the code
+// around the call.
+func fingerprint(function string, msg string) string {
+	var buf [256]byte
+	b := append(buf[:0], function...)
+	b = append(b, 0)
+	sum := sha256.Sum256(appendTemplate(b, msg))
+	return hex.EncodeToString(sum[:8])
+}
+
+// appendTemplate appends msg with the runs of digits replaced by a single '0',
+// so the messages formatted with counts, ids or durations have the same template.
+func appendTemplate(template []byte, msg string) []byte {
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= '0' && c <= '9' {
+			if i > 0 && msg[i-1] >= '0' && msg[i-1] <= '9' {
+				continue
+			}
+			c = '0'
+		}
+		template = append(template, c)
+	}
+	return template
+}
+
+// FingerprintCount is the number of error entries of a fingerprint.
+type FingerprintCount struct {
+	Fingerprint string
+
+	// Count is the number of times an entry was logged with the fingerprint,
+	// including the repetitions of coalesced entries.
+	Count int64
+
+	// Last is the last entry with the fingerprint, as a sample of the site.
+	// Its RepeatCount, LastTime and Stack are not filled.
+	Last Entry
+}
+
+// TopFingerprints returns the fingerprints of the entries of level ERROR or
+// above logged in the last window, the most frequent first, to see which error
+// sites are firing most. A zero window counts all the entries, and n limits the
+// number of fingerprints returned if positive. Files written by versions without
+// fingerprints are skipped.
+func (rd *Reader) TopFingerprints(ctx context.Context, window time.Duration, n int) ([]FingerprintCount, error) {
+	if window < 0 {
+		return nil, fmt.Errorf("negative window")
+	}
+	q := Query{MinLevel: slog.LevelError}
+	if window > 0 {
+		q.Since = time.Now().Add(-window)
+	}
+
+	rd.mu.RLock()
+	defer rd.mu.RUnlock()
+
+	counts, err := rd.fingerprintCounts(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return sortFingerprints(counts, n), nil
+}
+
+// fingerprintCounts counts the entries selected by q by fingerprint, with the
+// read lock held. Files written by versions without fingerprints are skipped.
+func (rd *Reader) fingerprintCounts(ctx context.Context, q Query) (map[string]*FingerprintCount, error) {
+	// The sample is taken from the row with the maximum time, as SQLite does for
+	// the bare columns of a query with a single MAX() aggregate
+	where, args := queryWhere(q, rd.indexedColumns)
+	if where == "" {
+		where = " WHERE fingerprint IS NOT NULL"
+	} else {
+		where += " AND fingerprint IS NOT NULL"
+	}
+	stmt := "SELECT fingerprint, SUM(repeat_count), MAX(epoch_secs * 1000000000 + nanos), rowid, level, content, " +
+		"source_file, source_line, function FROM entries" + where + " GROUP BY fingerprint"
+
+	counts := map[string]*FingerprintCount{}
+	for _, f := range rd.files {
+		ok, err := f.hasColumn(ctx, "fingerprint")
+		if err != nil {
+			return nil, fmt.Errorf("%s: reading fingerprints: %w", f.name, err)
+		}
+		if !ok {
+			continue
+		}
+
+		rows, err := f.db.QueryContext(ctx, stmt, args...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: reading fingerprints: %w", f.name, err)
+		}
+		for rows.Next() {
+			var fp string
+			var count, last int64
+			var level int
+			var e Entry
+			var sourceLine sql.NullInt64
+			var sourceFile, function sql.NullString
+			if err := rows.Scan(&fp, &count, &last, &e.ID, &level, &e.Content, &sourceFile, &sourceLine, &function); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("%s: reading fingerprints: %w", f.name, err)
+			}
+			e.Time = time.Unix(0, last)
+			e.Level = slog.Level(level)
+			e.File = f.name
+			e.Fingerprint = fp
+			if sourceFile.Valid {
+				e.Source = &slog.Source{Function: function.String, File: sourceFile.String, Line: int(sourceLine.Int64)}
+			}
+
+			c := counts[fp]
+			if c == nil {
+				c = &FingerprintCount{Fingerprint: fp}
+				counts[fp] = c
+			}
+			c.Count += count
+			if c.Last.Time.Before(e.Time) {
+				c.Last = e
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("%s: reading fingerprints: %w", f.name, err)
+		}
+	}
+	return counts, nil
+}
+
+// sortFingerprints returns the counts the most frequent first, at most n if positive.
+func sortFingerprints(counts map[string]*FingerprintCount, n int) []FingerprintCount {
+	top := make([]FingerprintCount, 0, len(counts))
+	for _, c := range counts {
+		top = append(top, *c)
+	}
+	slices.SortFunc(top, func(a, b FingerprintCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), b.Last.Time.Compare(a.Last.Time), cmp.Compare(a.Fingerprint, b.Fingerprint))
+	})
+	if n > 0 && len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
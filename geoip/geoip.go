@@ -0,0 +1,216 @@
+// Package geoip resolves the remote address of the records to the country and
+// the autonomous system of the client, with local MaxMind DB files like the
+// GeoLite2 Country and ASN databases, for the services using sqlogger as their
+// access log:
+//
+//	geo, err := geoip.Open("GeoLite2-Country.mmdb", "GeoLite2-ASN.mmdb")
+//	if err != nil {
+//		return err
+//	}
+//	logger, err := sqlogger.NewSQLogger(&sqlogger.Options{
+//		Enrichers: []func(r *slog.Record){geo.Enricher(geoip.RemoteAddrKey)},
+//	})
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+const (
+	// RemoteAddrKey is the usual key of the remote address, like the RemoteAddr of
+	// an http.Request: "203.0.113.7:51234", "[2001:db8::1]:443" or "203.0.113.7".
+	RemoteAddrKey = "remote_addr"
+
+	// The keys of the attributes added by the enricher
+	CountryKey = "remote_country"
+	ASNKey     = "remote_asn"
+	ASOrgKey   = "remote_as_org"
+)
+
+// Location is what the databases know about an address.
+type Location struct {
+	// Country is the ISO 3166-1 code of the country, like "ES".
+	Country string
+
+	// ASN and ASOrg are the number and the organization of the autonomous system.
+	ASN   uint64
+	ASOrg string
+}
+
+// DB looks up addresses in a set of MaxMind DB files. It is safe for concurrent use.
+type DB struct {
+	names []string
+	files []*maxminddb.Reader
+
+	// The locations already decoded, by file and offset in its data section. They
+	// are bounded by the records of the files.
+	cache sync.Map
+}
+
+type cacheKey struct {
+	file   int
+	offset uintptr
+}
+
+// record has the fields of the Country and ASN databases used for a Location.
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+	ASN   uint64 `maxminddb:"autonomous_system_number"`
+	ASOrg string `maxminddb:"autonomous_system_organization"`
+}
+
+// Open loads the MaxMind DB files in memory. The locations are combined from all
+// of them, the first file having a field taking precedence, so a Country and an
+// ASN database can be used together.
+func Open(names ...string) (*DB, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no database files")
+	}
+
+	db := &DB{names: names}
+	for _, name := range names {
+		buf, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		f, err := maxminddb.FromBytes(buf)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		db.files = append(db.files, f)
+	}
+	return db, nil
+}
+
+// Lookup returns the location of addr. The fields not found are empty. If some
+// file can not be read, the fields found in the others are returned with the error.
+func (db *DB) Lookup(addr netip.Addr) (Location, error) {
+	addr = addr.Unmap()
+
+	var loc Location
+	var errs []error
+	for i, f := range db.files {
+		if addr.Is6() && f.Metadata.IPVersion == 4 {
+			continue
+		}
+		offset, err := f.LookupOffset(net.IP(addr.AsSlice()))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", db.names[i], err))
+			continue
+		}
+		if offset == maxminddb.NotFound {
+			continue
+		}
+
+		l, err := db.location(i, offset)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if loc.Country == "" {
+			loc.Country = l.Country
+		}
+		if loc.ASN == 0 {
+			loc.ASN, loc.ASOrg = l.ASN, l.ASOrg
+		}
+	}
+	return loc, errors.Join(errs...)
+}
+
+// location decodes the location in a record of a file.
+func (db *DB) location(file int, offset uintptr) (Location, error) {
+	key := cacheKey{file, offset}
+	if l, ok := db.cache.Load(key); ok {
+		return l.(Location), nil
+	}
+
+	var rec record
+	if err := db.files[file].Decode(offset, &rec); err != nil {
+		return Location{}, fmt.Errorf("%s: %w", db.names[file], err)
+	}
+
+	l := Location{Country: rec.Country.ISOCode, ASN: rec.ASN, ASOrg: rec.ASOrg}
+	if l.Country == "" {
+		l.Country = rec.RegisteredCountry.ISOCode
+	}
+
+	db.cache.Store(key, l)
+	return l, nil
+}
+
+// Enricher returns a function for sqlogger.Options.Enrichers which adds the
+// country and autonomous system of the address in the attribute key of the
+// records, with the keys CountryKey, ASNKey and ASOrgKey. Only the attributes of
+// the record are looked at, not those added with WithAttrs. Records whose address
+// can not be parsed or is not in the databases, like private addresses, are left
+// unchanged.
+func (db *DB) Enricher(key string) func(r *slog.Record) {
+	return func(r *slog.Record) {
+		var addr netip.Addr
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key != key {
+				return true
+			}
+			addr = parseAddr(a.Value.Resolve())
+			return false
+		})
+		if !addr.IsValid() {
+			return
+		}
+
+		// A corrupt file does not hide what the others know
+		loc, _ := db.Lookup(addr)
+		if loc.Country != "" {
+			r.AddAttrs(slog.String(CountryKey, loc.Country))
+		}
+		if loc.ASN != 0 {
+			r.AddAttrs(slog.Uint64(ASNKey, loc.ASN), slog.String(ASOrgKey, loc.ASOrg))
+		}
+	}
+}
+
+// parseAddr returns the address in v, with or without a port, or the zero Addr.
+func parseAddr(v slog.Value) netip.Addr {
+	var s string
+	switch v.Kind() {
+	case slog.KindString:
+		s = v.String()
+	case slog.KindAny:
+		switch a := v.Any().(type) {
+		case netip.Addr:
+			return a
+		case netip.AddrPort:
+			return a.Addr()
+		case net.IP:
+			addr, _ := netip.AddrFromSlice(a)
+			return addr
+		case fmt.Stringer:
+			s = a.String()
+		default:
+			return netip.Addr{}
+		}
+	default:
+		return netip.Addr{}
+	}
+
+	s = strings.TrimSpace(s)
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr()
+	}
+	addr, _ := netip.ParseAddr(strings.Trim(s, "[]"))
+	return addr
+}
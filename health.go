@@ -0,0 +1,80 @@
+package sqlogger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+const defaultMaxHealthyWALSize = 64 << 20
+
+// Healthy checks that the logging subsystem is able to store new entries: the
+// store is writable and, with the default store, the WAL is under its size limit,
+// and the retry queue of failed entries is not saturated.
+func (h *SQLogger) Healthy(ctx context.Context) error {
+	var errs []error
+
+	if hc, ok := h.store.(HealthChecker); ok {
+		if err := hc.Healthy(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	failures := h.WriteFailures()
+	if h.retry.size > 0 && failures.Deferred >= h.retry.size {
+		errs = append(errs, fmt.Errorf("retry queue is full with %d entries", failures.Deferred))
+	}
+
+	return errors.Join(errs...)
+}
+
+// HealthHandler returns an http.Handler responding 200 when Healthy succeeds and
+// 503 with the error otherwise, suitable for readiness probes.
+func (h *SQLogger) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := h.Healthy(r.Context()); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, err)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
+
+// Healthy checks that the live database can take the write lock, and that its WAL
+// is not larger than the limit, which would mean that checkpoints are not progressing.
+// It also reports the error of the last background checkpoint, if any.
+func (s *SQLiteStore) Healthy(ctx context.Context) error {
+	s.mu.Lock()
+	db := s.db
+	name := s.currentName
+	checkpointErr := s.checkpointErr
+	s.mu.Unlock()
+
+	if checkpointErr != nil {
+		return checkpointErr
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", name, err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return fmt.Errorf("%s is not writable: %w", name, err)
+	}
+	if _, err := conn.ExecContext(ctx, "ROLLBACK"); err != nil {
+		return fmt.Errorf("%s is not writable: %w", name, err)
+	}
+
+	info, err := os.Stat(name + "-wal")
+	if err == nil && info.Size() > s.maxHealthyWALSize {
+		return fmt.Errorf("WAL of %s is %d bytes, over the limit of %d", name, info.Size(), s.maxHealthyWALSize)
+	}
+
+	return nil
+}
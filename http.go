@@ -0,0 +1,151 @@
+package sqlogger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const defaultRequestIDHeader = "X-Request-Id"
+
+// HTTPOptions configures HTTPMiddleware.
+type HTTPOptions struct {
+	// Message is the message of the entries. If empty, "http request" is used.
+	Message string
+
+	// RequestIDHeader is the header with the ID of the request set by a proxy,
+	// which is also set in the response. Requests without it get a random ID.
+	// If empty, "X-Request-Id" is used.
+	RequestIDHeader string
+
+	// Level returns the level of the entry of a request with the given status.
+	// If nil, 5xx responses are logged as ERROR, 4xx as WARN and the rest as INFO.
+	Level func(status int) slog.Level
+
+	// Skip, if not nil, returns true for the requests which are not logged, like
+	// the health probes.
+	Skip func(r *http.Request) bool
+}
+
+type requestIDKey struct{}
+
+// RequestID returns the ID of the request set by HTTPMiddleware in the context,
+// or an empty string.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// HTTPMiddleware returns a middleware logging an entry for every request, with
+// the attributes http.method, http.path, http.status, http.latency, http.bytes,
+// http.remote_addr and http.request_id, so the store doubles as an access log.
+// With Options.IndexedAttrs like "http.status" and "http.latency", requests can be
+// selected by status code and latency with plain SQL.
+// The ID of the request is available to the handlers with RequestID.
+func HTTPMiddleware(logger *slog.Logger, opts *HTTPOptions) func(http.Handler) http.Handler {
+	var o HTTPOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Message == "" {
+		o.Message = "http request"
+	}
+	if o.RequestIDHeader == "" {
+		o.RequestIDHeader = defaultRequestIDHeader
+	}
+	if o.Level == nil {
+		o.Level = statusLevel
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if o.Skip != nil && o.Skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+
+			id := r.Header.Get(o.RequestIDHeader)
+			if id == "" {
+				id = newRequestID()
+			}
+			w.Header().Set(o.RequestIDHeader, id)
+			r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+
+			if sw.status == 0 {
+				sw.status = http.StatusOK
+			}
+
+			logger.LogAttrs(r.Context(), o.Level(sw.status), o.Message,
+				slog.Group("http",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Int("status", sw.status),
+					slog.Duration("latency", time.Since(start)),
+					slog.Int64("bytes", sw.bytes),
+					slog.String("remote_addr", r.RemoteAddr),
+					slog.String("request_id", id),
+				),
+			)
+		})
+	}
+}
+
+// statusLevel is the default level of the entry of a request.
+func statusLevel(status int) slog.Level {
+	switch {
+	case status >= 500:
+		return slog.LevelError
+	case status >= 400:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// newRequestID returns a random ID of 16 hexadecimal digits.
+func newRequestID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// statusWriter records the status and the size of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush supports streaming responses.
+func (w *statusWriter) Flush() {
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap gives http.ResponseController access to the original writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
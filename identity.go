@@ -0,0 +1,75 @@
+package sqlogger
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Every database file of SQLiteStore records in the meta table the process which
+// wrote it, so files collected from several instances can be told apart.
+const metaSQL = `
+DROP TABLE IF EXISTS meta;
+
+CREATE TABLE meta (
+  service TEXT,
+  hostname TEXT,
+  pid INTEGER,
+  started_epoch_secs LONG
+);
+`
+
+// Identity identifies the process writing the entries.
+type Identity struct {
+	// Service is the name of the service, from Options.Service.
+	Service string
+
+	Hostname string
+	PID      int
+}
+
+// IdentitySetter is implemented by stores which record the identity of the
+// process writing the entries. SetIdentity is called before Open.
+type IdentitySetter interface {
+	SetIdentity(id Identity)
+}
+
+// newIdentity captures the identity of the current process.
+func newIdentity(service string) Identity {
+	if service == "" {
+		service = filepath.Base(os.Args[0])
+	}
+	hostname, _ := os.Hostname()
+	return Identity{
+		Service:  service,
+		Hostname: hostname,
+		PID:      os.Getpid(),
+	}
+}
+
+// Identity returns the identity of the process recorded with the entries.
+func (h *SQLogger) Identity() Identity {
+	return h.identity
+}
+
+// SetIdentity sets the identity recorded in the meta table of each database file.
+func (s *SQLiteStore) SetIdentity(id Identity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.identity = id
+}
+
+// writeMeta records the identity of the process in a freshly created database.
+func (s *SQLiteStore) writeMeta(db *sql.DB) error {
+	if _, err := db.Exec(metaSQL); err != nil {
+		return fmt.Errorf("creating meta table: %w", err)
+	}
+	_, err := db.Exec("INSERT INTO meta (service, hostname, pid, started_epoch_secs) VALUES (?, ?, ?, ?)",
+		s.identity.Service, s.identity.Hostname, s.identity.PID, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("writing meta table: %w", err)
+	}
+	return nil
+}
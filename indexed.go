@@ -0,0 +1,67 @@
+package sqlogger
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var invalidColumnChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// reservedColumns are the columns of the entries table which can not be used for
+// indexed attributes.
+var reservedColumns = []string{
+	"rowid", "epoch_secs", "nanos", "level", "content", "repeat_count", "last_epoch_secs",
+	"last_nanos", "source_file", "source_line", "function", "stack", "fingerprint", "hash",
+}
+
+// indexedColumns returns the name of the column of each indexed attribute, which is
+// the key with the characters not valid in a column name replaced by underscores.
+func indexedColumns(keys []string) (map[string]string, error) {
+	columns := map[string]string{}
+	for _, key := range keys {
+		column := invalidColumnChars.ReplaceAllString(key, "_")
+		if column == "" || (column[0] >= '0' && column[0] <= '9') {
+			column = "attr_" + column
+		}
+		// SQLite compares the column names without case
+		if slices.ContainsFunc(reservedColumns, func(c string) bool { return strings.EqualFold(c, column) }) {
+			return nil, fmt.Errorf("indexed attribute %q conflicts with column %s", key, column)
+		}
+		for other, c := range columns {
+			if c == column {
+				return nil, fmt.Errorf("indexed attributes %q and %q map to the same column %s", other, key, column)
+			}
+		}
+		columns[key] = column
+	}
+	return columns, nil
+}
+
+// createIndexedColumns adds a column and an index for every indexed attribute. The
+// columns have no type affinity, so the values are stored as in the attrs table.
+func (s *SQLiteStore) createIndexedColumns(db *sql.DB) error {
+	for _, key := range s.indexedAttrs {
+		column := s.indexedColumns[key]
+		stmt := fmt.Sprintf("ALTER TABLE entries ADD COLUMN %s; CREATE INDEX entries_%s ON entries (%s);", column, column, column)
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("creating column for indexed attribute %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// indexedValues returns the values of the indexed attributes of an entry, in the
+// order of their columns. When an attribute is repeated, the last value is used.
+func (s *SQLiteStore) indexedValues(attrs []slog.Attr) []any {
+	values := make([]any, len(s.indexedAttrs))
+	for _, a := range attrs {
+		if i := slices.Index(s.indexedAttrs, a.Key); i >= 0 {
+			_, values[i] = attrColumnValue(a.Value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,53 @@
+package sqlogger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ErrLocked is returned by Open when the files of the store are being written by
+// another process. Give each process its own Instance to share a directory.
+var ErrLocked = errors.New("the log files are in use by another process")
+
+var validInstance = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// lockName returns the path of the lock file of the store.
+func (s *SQLiteStore) lockName() string {
+	return filepath.Join(s.dir, s.basename+".lock")
+}
+
+// lock takes the lock file of the store, so only one process writes its files.
+func (s *SQLiteStore) lock() error {
+	f, err := os.OpenFile(s.lockName(), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening lock file: %w", err)
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		if errors.Is(err, ErrLocked) {
+			return fmt.Errorf("%w: %s", ErrLocked, s.lockName())
+		}
+		return fmt.Errorf("locking %s: %w", s.lockName(), err)
+	}
+
+	// The PID of the owner helps finding the process holding the files
+	f.Truncate(0)
+	fmt.Fprintf(f, "%d\n", os.Getpid())
+
+	s.lockFile = f
+	return nil
+}
+
+// unlock releases the lock file of the store.
+func (s *SQLiteStore) unlock() error {
+	if s.lockFile == nil {
+		return nil
+	}
+	err := s.lockFile.Close()
+	s.lockFile = nil
+	return err
+}
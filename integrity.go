@@ -0,0 +1,68 @@
+package sqlogger
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// The extension added to the database files found corrupted when opening the store.
+const corruptExtension = ".corrupt"
+
+// recoverCorrupt checks the integrity of the database file name, if it exists, and
+// moves it aside to name.corrupt with its WAL when it is corrupted, like after a
+// power loss, so a fresh file is created instead of failing. A previous file moved
+// aside with the same name is replaced.
+func (s *SQLiteStore) recoverCorrupt(name string) error {
+	if _, err := os.Stat(name); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	problem, err := s.quickCheck(name)
+	if err != nil || problem == "" {
+		return err
+	}
+
+	moved := name + corruptExtension
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Rename(name+suffix, moved+suffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("moving aside the corrupted log file %s: %w", name, err)
+		}
+	}
+
+	slog.Warn("corrupted log file moved aside", "file", name, "moved_to", moved, "problem", problem)
+	return nil
+}
+
+// quickCheck returns the first problem found by PRAGMA quick_check in the database
+// file name, or "" if it is sound.
+func (s *SQLiteStore) quickCheck(name string) (string, error) {
+	db, err := s.openDB(name)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	var result string
+	err = db.QueryRow("PRAGMA quick_check(1)").Scan(&result)
+
+	// The messages of SQLITE_CORRUPT and SQLITE_NOTADB, matched by text as the error
+	// type of the driver requires cgo. An encrypted file read with the wrong key is
+	// not a database either.
+	if err != nil {
+		msg := err.Error()
+		if strings.Contains(msg, "database disk image is malformed") ||
+			(strings.Contains(msg, "file is not a database") && s.encryptionKey == nil) {
+			return msg, nil
+		}
+		return "", fmt.Errorf("checking the integrity of %s: %w", name, err)
+	}
+
+	if result != "ok" {
+		return result, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,95 @@
+//go:build linux
+
+package sqlogger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const journaldSocket = "/run/systemd/journal/socket"
+
+// JournaldSink forwards the entries to systemd-journald using its native protocol,
+// with the attributes as journal fields.
+type JournaldSink struct {
+	conn       *net.UnixConn
+	identifier string
+}
+
+// NewJournaldSink connects to the local journald socket, identifying the messages
+// with identifier. If identifier is empty, the program name is used.
+func NewJournaldSink(identifier string) (*JournaldSink, error) {
+	if identifier == "" {
+		identifier = filepath.Base(os.Args[0])
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journaldSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+
+	return &JournaldSink{conn: conn, identifier: identifier}, nil
+}
+
+func (s *JournaldSink) Send(e *Entry) error {
+	var b bytes.Buffer
+
+	appendJournalField(&b, "MESSAGE", sinkText(e))
+	appendJournalField(&b, "PRIORITY", strconv.Itoa(severity(e.Level)))
+	appendJournalField(&b, "SYSLOG_IDENTIFIER", s.identifier)
+	if e.Source != nil {
+		appendJournalField(&b, "CODE_FILE", e.Source.File)
+		appendJournalField(&b, "CODE_LINE", strconv.Itoa(e.Source.Line))
+		appendJournalField(&b, "CODE_FUNC", e.Source.Function)
+	}
+	for _, a := range e.Attrs {
+		appendJournalField(&b, journalFieldName(a.Key), a.Value.String())
+	}
+
+	_, err := s.conn.Write(b.Bytes())
+	return err
+}
+
+func (s *JournaldSink) Close() error {
+	return s.conn.Close()
+}
+
+// appendJournalField writes a field in the journald native format, using the
+// binary form for values which span several lines.
+func appendJournalField(b *bytes.Buffer, name string, value string) {
+	b.WriteString(name)
+	if !strings.ContainsRune(value, '\n') {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+
+	b.WriteByte('\n')
+	binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// journalFieldName converts an attribute key into a valid journal field name:
+// uppercase letters, digits and underscores, not starting with an underscore.
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, key)
+
+	name = strings.TrimLeft(name, "_")
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "ATTR_" + name
+	}
+	return name
+}
@@ -0,0 +1,51 @@
+package sqlogger
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// levelRule returns the minimum level of the handler from Options.LevelRules, or
+// nil if no rule matches its groups and attributes.
+// The rule with the longest key among those matching is used.
+func (h *SQLogger) levelRule() slog.Leveler {
+	var rule slog.Leveler
+	var ruleKey string
+
+	match := func(key string) {
+		if r, ok := h.opts.LevelRules[key]; ok && len(key) > len(ruleKey) {
+			rule, ruleKey = r, key
+		}
+	}
+
+	var path string
+	for _, goa := range h.goas {
+		if goa.group != "" {
+			if path != "" {
+				path += "."
+			}
+			path += goa.group
+			match(path)
+			continue
+		}
+		for _, a := range goa.attrs {
+			if a.Value.Kind() == slog.KindString {
+				match(a.Key + "=" + a.Value.String())
+			}
+		}
+	}
+
+	return rule
+}
+
+// validLevelRuleKey reports whether a key of Options.LevelRules is a group path
+// like "http.client" or an attribute like "component=db".
+func validLevelRuleKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	if k, v, ok := strings.Cut(key, "="); ok {
+		return k != "" && v != ""
+	}
+	return !strings.HasPrefix(key, ".") && !strings.HasSuffix(key, ".") && !strings.Contains(key, "..")
+}
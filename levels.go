@@ -0,0 +1,58 @@
+package sqlogger
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// Additional levels, for use with Options.LevelNames.
+const (
+	LevelTrace = slog.Level(-8)
+	LevelFatal = slog.Level(12)
+)
+
+// LevelStorage configures how the entries of a level are stored.
+type LevelStorage struct {
+	// Sync makes every entry of the level durable as soon as it is inserted, if
+	// the store implements Syncer. Use it for the levels which must never be lost,
+	// like audit records.
+	Sync bool
+
+	// Skip prints the entries of the level to the console and the sinks, but does
+	// not store them, like for very verbose trace records.
+	Skip bool
+}
+
+// Syncer is implemented by stores which can make the entries inserted so far durable.
+type Syncer interface {
+	Sync() error
+}
+
+// levelName returns the name of a level, as configured in Options.LevelNames.
+func (h *SQLogger) levelName(l slog.Level) string {
+	if name, ok := h.levelNames[l]; ok {
+		return name
+	}
+	return l.String()
+}
+
+// resolveLevels resolves the keys of the per-level options to plain levels.
+func resolveLevels[V any](m map[slog.Leveler]V) map[slog.Level]V {
+	resolved := make(map[slog.Level]V, len(m))
+	for leveler, v := range m {
+		resolved[leveler.Level()] = v
+	}
+	return resolved
+}
+
+// Sync checkpoints the WAL into the database file, so the entries inserted so far
+// survive a power loss.
+func (s *SQLiteStore) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.db.Exec("PRAGMA wal_checkpoint(FULL)"); err != nil {
+		return fmt.Errorf("syncing log database: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package sqlogger
+
+import "os"
+
+// lockFile does nothing in the platforms without file locks.
+func lockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,305 @@
+// Package loki implements a sqlogger.Sink which batches the entries and pushes
+// them to the HTTP push API of Grafana Loki, so the local SQLite files remain the
+// store of each service while Loki gets the view of the whole fleet.
+//
+//	exporter := loki.New(&loki.Options{
+//		URL:        "http://loki:3100/loki/api/v1/push",
+//		Labels:     map[string]string{"service": "orders"},
+//		LabelAttrs: []string{"http.method"},
+//	})
+//	logger, err := sqlogger.NewSQLogger(&sqlogger.Options{Sinks: []sqlogger.Sink{exporter}})
+package loki
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hesusruiz/sqlogger"
+)
+
+const defaultBatchSize = 1000
+const defaultFlushInterval = time.Second
+const defaultTimeout = 10 * time.Second
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// Options configures an Exporter.
+type Options struct {
+	// URL is the push endpoint, like "http://localhost:3100/loki/api/v1/push".
+	URL string
+
+	// Labels are added to all the streams.
+	Labels map[string]string
+
+	// LabelAttrs are the keys of the attributes promoted to stream labels, qualified
+	// by their groups like "http.method". Dots and other characters not allowed in
+	// label names are replaced by underscores. Keep them to low-cardinality values.
+	LabelAttrs []string
+
+	// TenantID is sent in the X-Scope-OrgID header for multi-tenant Loki.
+	TenantID string
+
+	// Username and Password are used for HTTP basic authentication, if set.
+	Username string
+	Password string
+
+	// BatchSize is the number of entries which triggers a push. If zero, 1000 is used.
+	BatchSize int
+
+	// FlushInterval is the maximum time an entry waits before being pushed.
+	// If zero, one second is used.
+	FlushInterval time.Duration
+
+	// Client is the HTTP client for the pushes.
+	// If nil, a client with a timeout of 10 seconds is used.
+	Client *http.Client
+
+	// OnError, if not nil, is called when a batch could not be pushed.
+	// By default the error is printed to the default slog logger.
+	OnError func(err error)
+}
+
+// Exporter is a sqlogger.Sink pushing the entries to Loki in batches.
+type Exporter struct {
+	opts Options
+
+	mu      sync.Mutex
+	pending []line
+	full    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+var _ sqlogger.Sink = (*Exporter)(nil)
+
+// line is an entry waiting to be pushed, with its stream labels.
+type line struct {
+	labels string
+	time   time.Time
+	text   string
+}
+
+// New returns an Exporter and starts its background pusher.
+func New(opts *Options) *Exporter {
+	e := &Exporter{
+		full:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	if opts != nil {
+		e.opts = *opts
+	}
+	if e.opts.BatchSize == 0 {
+		e.opts.BatchSize = defaultBatchSize
+	}
+	if e.opts.FlushInterval == 0 {
+		e.opts.FlushInterval = defaultFlushInterval
+	}
+	if e.opts.Client == nil {
+		e.opts.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	if e.opts.OnError == nil {
+		e.opts.OnError = func(err error) {
+			slog.Error("pushing logs to Loki", "error", err)
+		}
+	}
+
+	go e.run()
+
+	return e
+}
+
+// Send queues the entry for the next push.
+func (e *Exporter) Send(entry *sqlogger.Entry) error {
+	labels := map[string]string{}
+	for k, v := range e.opts.Labels {
+		labels[k] = v
+	}
+	labels["level"] = strings.ToLower(entry.Level.String())
+
+	// Attributes promoted to labels are not repeated in the line
+	var attrs []slog.Attr
+	for _, a := range entry.Attrs {
+		if slices.Contains(e.opts.LabelAttrs, a.Key) {
+			labels[invalidLabelChars.ReplaceAllString(a.Key, "_")] = a.Value.String()
+			continue
+		}
+		attrs = append(attrs, a)
+	}
+
+	l := line{
+		labels: labelsKey(labels),
+		time:   entry.Time,
+		text:   logfmt(entry.Message, attrs),
+	}
+
+	e.mu.Lock()
+	e.pending = append(e.pending, l)
+	full := len(e.pending) >= e.opts.BatchSize
+	e.mu.Unlock()
+
+	if full {
+		select {
+		case e.full <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Close pushes the pending entries and stops the background pusher.
+func (e *Exporter) Close() error {
+	close(e.done)
+	<-e.stopped
+	return nil
+}
+
+func (e *Exporter) run() {
+	defer close(e.stopped)
+
+	ticker := time.NewTicker(e.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.full:
+		case <-e.done:
+			e.flush()
+			return
+		}
+		e.flush()
+	}
+}
+
+// flush pushes all the pending entries.
+func (e *Exporter) flush() {
+	e.mu.Lock()
+	batch := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := e.push(batch); err != nil {
+		e.opts.OnError(fmt.Errorf("dropping %d entries: %w", len(batch), err))
+	}
+}
+
+type pushRequest struct {
+	Streams []stream `json:"streams"`
+}
+
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// push sends a batch to Loki, grouping the lines by their labels.
+func (e *Exporter) push(batch []line) error {
+	streams := map[string]*stream{}
+	var req pushRequest
+
+	for _, l := range batch {
+		s, ok := streams[l.labels]
+		if !ok {
+			s = &stream{Stream: parseLabelsKey(l.labels)}
+			streams[l.labels] = s
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(l.time.UnixNano(), 10), l.text})
+	}
+	for _, s := range streams {
+		req.Streams = append(req.Streams, *s)
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(context.Background(), http.MethodPost, e.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if e.opts.TenantID != "" {
+		httpReq.Header.Set("X-Scope-OrgID", e.opts.TenantID)
+	}
+	if e.opts.Username != "" {
+		httpReq.SetBasicAuth(e.opts.Username, e.opts.Password)
+	}
+
+	resp, err := e.opts.Client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("loki returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
+
+// labelsKey encodes a label set as a string usable as a map key, with the labels
+// sorted by name.
+func labelsKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte(0)
+		b.WriteString(labels[name])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+func parseLabelsKey(key string) map[string]string {
+	labels := map[string]string{}
+	parts := strings.Split(key, "\x00")
+	for i := 0; i+1 < len(parts); i += 2 {
+		labels[parts[i]] = parts[i+1]
+	}
+	return labels
+}
+
+// logfmt renders the message and the attributes in logfmt format.
+func logfmt(msg string, attrs []slog.Attr) string {
+	var b strings.Builder
+	b.WriteString("msg=")
+	b.WriteString(strconv.Quote(msg))
+	for _, a := range attrs {
+		b.WriteByte(' ')
+		b.WriteString(a.Key)
+		b.WriteByte('=')
+		value := a.Value.String()
+		if strings.ContainsAny(value, " \"=") || value == "" {
+			value = strconv.Quote(value)
+		}
+		b.WriteString(value)
+	}
+	return b.String()
+}
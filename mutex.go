@@ -0,0 +1,30 @@
+package sqlogger
+
+import "context"
+
+// ctxMutex is a mutex whose wait can be abandoned when a context is done, so the
+// inserts limited by Options.WriteTimeout are not blocked by a slow writer holding it.
+// It must be created with newCtxMutex.
+type ctxMutex chan struct{}
+
+func newCtxMutex() ctxMutex {
+	return make(ctxMutex, 1)
+}
+
+func (m ctxMutex) Lock() {
+	m <- struct{}{}
+}
+
+func (m ctxMutex) Unlock() {
+	<-m
+}
+
+// LockContext locks the mutex, or returns the error of ctx if it is done first.
+func (m ctxMutex) LockContext(ctx context.Context) error {
+	select {
+	case m <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
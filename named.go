@@ -0,0 +1,92 @@
+package sqlogger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// namedSet holds the handlers returned by Named, shared by all the handlers derived
+// from the same root, with the options of the root.
+type namedSet struct {
+	root *SQLogger
+	opts Options
+
+	mu       sync.Mutex
+	handlers map[string]*SQLogger
+}
+
+// Named returns a handler writing its records to their own rotation set, the files
+// name.N.sqlite in the directory of h, with the same options, so the noisy
+// subsystems of large applications can be isolated:
+//
+//	payments, err := handler.Named("payments")
+//	...
+//	paymentsLogger := slog.New(payments)
+//
+// With Options.CopyNamedToMain, the records are also stored in the main files.
+// The console and the sinks receive the records once in both cases.
+// The attributes and groups of h are kept. Calling Named again with the same name
+// uses the same files. The named handlers are closed by the Close of the main
+// handler, and must not be closed otherwise. The main handler must use the
+// default store.
+func (h *SQLogger) Named(name string) (*SQLogger, error) {
+	set := h.named
+	if set == nil {
+		return nil, fmt.Errorf("named logs require the default store")
+	}
+	if !validInstance.MatchString(name) || name == logFileBasename {
+		return nil, fmt.Errorf("invalid log name %q", name)
+	}
+
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	named, ok := set.handlers[name]
+	if !ok {
+		opts := set.opts
+		opts.name = name
+		if opts.CopyNamedToMain {
+			// The main handler prints the records and sends them to the sinks
+			opts.Console = io.Discard
+			opts.Sinks = nil
+		}
+
+		var err error
+		if named, err = NewSQLogger(&opts); err != nil {
+			return nil, fmt.Errorf("opening log %s: %w", name, err)
+		}
+		named.named = set
+		named.isNamed = true
+		named.level = set.root.level
+		if opts.CopyNamedToMain {
+			named.main = set.root
+		}
+
+		if set.handlers == nil {
+			set.handlers = map[string]*SQLogger{}
+		}
+		set.handlers[name] = named
+	}
+
+	for _, goa := range h.goas {
+		named = named.withGroupOrAttrs(goa)
+	}
+	return named, nil
+}
+
+// close closes the named handlers.
+func (set *namedSet) close() error {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(set.handlers)) {
+		errs = append(errs, set.handlers[name].Close())
+	}
+	set.handlers = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,160 @@
+package sqlogger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RotationNaming is the naming scheme of the database files of SQLiteStore.
+type RotationNaming int
+
+const (
+	// ByNumber uses a fixed ring of files named logs.N.sqlite, with N from 0 to
+	// the number of files minus one. The live file is recreated when opened.
+	ByNumber RotationNaming = iota
+
+	// ByTimestamp names each file with the UTC time it was created, like
+	// logs.20240501T120000.sqlite. A new file is created when the store is opened,
+	// and the oldest files beyond the number of files are deleted.
+	ByTimestamp
+)
+
+const timestampLayout = "20060102T150405"
+
+var timestampPart = regexp.MustCompile(`^\d{8}T\d{6}(-\d+)?$`)
+
+// timestampName returns the name of a new file created at t, which sorts after
+// all the existing files even if they were created in the same second.
+func (s *SQLiteStore) timestampName(t time.Time) (string, error) {
+	stamp := t.UTC().Format(timestampLayout)
+	seq := 1
+
+	files, err := s.timestampFiles()
+	if err != nil {
+		return "", err
+	}
+	if len(files) > 0 {
+		lastStamp, lastSeq, _ := parseTimestampFile(files[len(files)-1], s.basename)
+		if lastStamp >= stamp {
+			stamp, seq = lastStamp, lastSeq+1
+		}
+	}
+
+	part := stamp
+	if seq > 1 {
+		part = fmt.Sprintf("%s-%d", stamp, seq)
+	}
+	return filepath.Join(s.dir, fmt.Sprintf("%s.%s.%s", s.basename, part, logFileExtension)), nil
+}
+
+// parseTimestampFile returns the timestamp and the sequence number in the same
+// second of a timestamped file with the given basename, plain or compressed.
+func parseTimestampFile(path string, basename string) (string, int, bool) {
+	parts := strings.Split(strings.TrimSuffix(filepath.Base(path), compressedExtension), ".")
+	if len(parts) != 3 || parts[0] != basename || parts[2] != logFileExtension || !timestampPart.MatchString(parts[1]) {
+		return "", 0, false
+	}
+	stamp, seqText, ok := strings.Cut(parts[1], "-")
+	if !ok {
+		return stamp, 1, true
+	}
+	seq, err := strconv.Atoi(seqText)
+	if err != nil {
+		return "", 0, false
+	}
+	return stamp, seq, true
+}
+
+// timestampFiles returns the paths of the timestamped files in the directory,
+// plain or compressed, oldest first.
+func (s *SQLiteStore) timestampFiles() ([]string, error) {
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type file struct {
+		stamp string
+		seq   int
+		path  string
+	}
+	var files []file
+
+	for _, entry := range dirEntries {
+		if entry.IsDir() {
+			continue
+		}
+		stamp, seq, ok := parseTimestampFile(entry.Name(), s.basename)
+		if !ok {
+			continue
+		}
+		files = append(files, file{stamp: stamp, seq: seq, path: filepath.Join(s.dir, entry.Name())})
+	}
+
+	slices.SortFunc(files, func(a, b file) int {
+		if c := strings.Compare(a.stamp, b.stamp); c != 0 {
+			return c
+		}
+		return a.seq - b.seq
+	})
+
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+	}
+	return paths, nil
+}
+
+// pruneTimestampFiles deletes the oldest timestamped files beyond the number of
+// files of the store.
+func (s *SQLiteStore) pruneTimestampFiles() error {
+	if s.appendOnly {
+		return nil
+	}
+
+	files, err := s.timestampFiles()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for len(files) > s.numLogFiles {
+		name := strings.TrimSuffix(files[0], compressedExtension)
+		for _, suffix := range []string{"", "-wal", "-shm", compressedExtension} {
+			if err := os.Remove(name + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+		files = files[1:]
+	}
+
+	return errors.Join(errs...)
+}
+
+// ringOrder returns the paths of the existing files of the store, plain or
+// compressed, from the oldest to the live one.
+func (s *SQLiteStore) ringOrder() ([]string, error) {
+	if s.naming == ByTimestamp {
+		return s.timestampFiles()
+	}
+
+	s.mu.Lock()
+	current := s.currentLogId
+	s.mu.Unlock()
+
+	// The oldest file is the one following the live one in the ring
+	var names []string
+	for i := 1; i <= s.numLogFiles; i++ {
+		if name := s.ringFile((current + i) % s.numLogFiles); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,103 @@
+package sqlogger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// PanicMessage is the message of the records logged for recovered panics.
+const PanicMessage = "panic recovered"
+
+// RecoverAndLog recovers a panic and logs it at ERROR level with the stack trace
+// of the panicking goroutine, making it durable in the database before returning.
+// It must be called directly by defer:
+//
+//	defer sqlogger.RecoverAndLog(logger)
+func RecoverAndLog(logger *slog.Logger) {
+	if v := recover(); v != nil {
+		logPanic(logger, v)
+	}
+}
+
+// RecoverAndRepanic is like RecoverAndLog, but panics again with the same value
+// after the panic has been logged, so the program still crashes.
+func RecoverAndRepanic(logger *slog.Logger) {
+	if v := recover(); v != nil {
+		logPanic(logger, v)
+		panic(v)
+	}
+}
+
+// Go runs f in a new goroutine, logging any panic in it with RecoverAndLog
+// instead of crashing the program.
+func Go(logger *slog.Logger, f func()) {
+	go func() {
+		defer RecoverAndLog(logger)
+		f()
+	}()
+}
+
+// logPanic logs a recovered panic value. With a SQLogger the stack is stored in the
+// stack column and the store is synced; other handlers get it as an attribute.
+func logPanic(logger *slog.Logger, v any) {
+	ctx := context.Background()
+
+	// The record is logged from the function which panicked
+	frames := panicFrames()
+	var pc uintptr
+	if len(frames) > 0 {
+		pc = frames[0].PC + 1
+	}
+	r := slog.NewRecord(time.Now(), slog.LevelError, PanicMessage, pc)
+	r.AddAttrs(slog.String("panic", fmt.Sprint(v)))
+
+	h, ok := logger.Handler().(*SQLogger)
+	if !ok {
+		r.AddAttrs(slog.String("stack", string(debug.Stack())))
+		logger.Handler().Handle(ctx, r)
+		return
+	}
+
+	stack := make([]slog.Source, len(frames))
+	for i, f := range frames {
+		stack[i] = slog.Source{Function: f.Function, File: h.relativeFile(f.File), Line: f.Line}
+	}
+
+	h.handle(ctx, r, stack)
+	h.Sync()
+}
+
+// panicFrames returns the stack of the panicking goroutine, from the function which
+// panicked, when called from a deferred function.
+func panicFrames() []runtime.Frame {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []runtime.Frame
+	panicking := false
+	for {
+		f, more := frames.Next()
+		switch {
+		case f.Function == "runtime.gopanic":
+			panicking = true
+		case !panicking:
+			// The frames of the recovery
+		case len(stack) == 0 && strings.HasPrefix(f.Function, "runtime."):
+			// The frames of the runtime raising the panic, like for a nil dereference
+		case f.Function == "runtime.main" || f.Function == "runtime.goexit":
+		default:
+			stack = append(stack, f)
+		}
+		if !more {
+			break
+		}
+	}
+
+	return stack
+}
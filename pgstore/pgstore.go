@@ -0,0 +1,351 @@
+// Package pgstore implements a sqlogger.Store writing the log entries to a
+// PostgreSQL database, so a fleet of services can log into a central store
+// using the same handler.
+//
+// Rotation is implemented with generations: every entry is tagged with its writer
+// and the generation of the writer which was live when it was inserted, and
+// rotating starts a new generation and deletes the entries of the generations of
+// the writer beyond the retention, leaving those of the other writers alone.
+package pgstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hesusruiz/sqlogger"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+const defaultTable = "sqlogger_entries"
+const defaultNumGenerations = 7
+
+var validTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Options configures a Store.
+type Options struct {
+	// Table is the name of the table for the entries.
+	// If empty, "sqlogger_entries" is used.
+	Table string
+
+	// NumGenerations is the number of generations kept in the table, the
+	// equivalent of the number of files in the SQLite rotation.
+	// If zero, 7 generations are kept.
+	NumGenerations int
+
+	// Instance names the writer of the entries, whose generations are independent
+	// of those of the other writers sharing the table. A process using the same
+	// Instance after a restart continues its generations, and its rotations delete
+	// the entries of its previous runs. Two processes must never use the same
+	// Instance at the same time.
+	// If empty, the writer is named after the service, hostname and PID of the
+	// process, like "orders@host1#1234", so every run starts its own generations.
+	Instance string
+}
+
+// Store is a sqlogger.Store backed by PostgreSQL.
+type Store struct {
+	dsn            string
+	table          string
+	numGenerations int
+
+	mu         sync.Mutex
+	instance   string
+	identity   sqlogger.Identity
+	writer     string
+	db         *sql.DB
+	generation int64
+	sequence   int64
+	lastId     int64
+}
+
+var (
+	_ sqlogger.Store          = (*Store)(nil)
+	_ sqlogger.Repeater       = (*Store)(nil)
+	_ sqlogger.HealthChecker  = (*Store)(nil)
+	_ sqlogger.IdentitySetter = (*Store)(nil)
+)
+
+// New returns a Store connecting to the PostgreSQL database specified by dsn,
+// either a URL or a DSN in key=value format.
+// The connection is not established until Open is called.
+func New(dsn string, opts *Options) (*Store, error) {
+	s := &Store{
+		dsn:            dsn,
+		table:          defaultTable,
+		numGenerations: defaultNumGenerations,
+	}
+
+	if opts != nil {
+		if opts.Table != "" {
+			s.table = opts.Table
+		}
+		if opts.NumGenerations != 0 {
+			s.numGenerations = opts.NumGenerations
+		}
+		s.instance = opts.Instance
+	}
+
+	if !validTableName.MatchString(s.table) {
+		return nil, fmt.Errorf("invalid table name %q", s.table)
+	}
+
+	return s, nil
+}
+
+func (s *Store) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	db, err := sql.Open("pgx", s.dsn)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS ` + s.table + ` (
+  id BIGSERIAL PRIMARY KEY,
+  generation BIGINT NOT NULL,
+  epoch_secs BIGINT NOT NULL,
+  nanos INTEGER NOT NULL,
+  level INTEGER NOT NULL,
+  content TEXT NOT NULL
+);
+ALTER TABLE ` + s.table + ` ADD COLUMN IF NOT EXISTS repeat_count BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE ` + s.table + ` ADD COLUMN IF NOT EXISTS last_epoch_secs BIGINT;
+ALTER TABLE ` + s.table + ` ADD COLUMN IF NOT EXISTS last_nanos INTEGER;
+ALTER TABLE ` + s.table + ` ADD COLUMN IF NOT EXISTS source_file TEXT;
+ALTER TABLE ` + s.table + ` ADD COLUMN IF NOT EXISTS source_line INTEGER;
+ALTER TABLE ` + s.table + ` ADD COLUMN IF NOT EXISTS function TEXT;
+ALTER TABLE ` + s.table + ` ADD COLUMN IF NOT EXISTS stack JSONB;
+ALTER TABLE ` + s.table + ` ADD COLUMN IF NOT EXISTS service TEXT;
+ALTER TABLE ` + s.table + ` ADD COLUMN IF NOT EXISTS hostname TEXT;
+ALTER TABLE ` + s.table + ` ADD COLUMN IF NOT EXISTS pid INTEGER;
+ALTER TABLE ` + s.table + ` ADD COLUMN IF NOT EXISTS fingerprint TEXT;
+ALTER TABLE ` + s.table + ` ADD COLUMN IF NOT EXISTS writer TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS ` + s.table + `_writer_generation_idx ON ` + s.table + ` (writer, generation);
+CREATE INDEX IF NOT EXISTS ` + s.table + `_time_idx ON ` + s.table + ` (epoch_secs, nanos);`)
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("creating table %s: %w", s.table, err)
+	}
+
+	s.writer = s.instance
+	if s.writer == "" {
+		s.writer = fmt.Sprintf("%s@%s#%d", s.identity.Service, s.identity.Hostname, s.identity.PID)
+	}
+
+	// Continue with the last generation written by the same writer, if any
+	err = db.QueryRow(`SELECT COALESCE(MAX(generation), 0) FROM `+s.table+` WHERE writer = $1`, s.writer).Scan(&s.generation)
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("reading current generation: %w", err)
+	}
+
+	err = db.QueryRow(`SELECT COUNT(*) FROM `+s.table+` WHERE writer = $1 AND generation = $2`, s.writer, s.generation).Scan(&s.sequence)
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("counting entries of current generation: %w", err)
+	}
+
+	s.db = db
+
+	return nil
+}
+
+// SetIdentity sets the identity stored in every row, as the entries of many
+// processes share the table.
+func (s *Store) SetIdentity(id sqlogger.Identity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.identity = id
+}
+
+// CountLive returns the number of entries of the current generation of the writer.
+func (s *Store) CountLive() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sequence, nil
+}
+
+func (s *Store) Insert(e sqlogger.Entry) (int64, error) {
+	return s.InsertContext(context.Background(), e)
+}
+
+// InsertContext inserts an entry like Insert, abandoning it if ctx is done.
+func (s *Store) InsertContext(ctx context.Context, e sqlogger.Entry) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sourceFile, function sql.NullString
+	var sourceLine sql.NullInt64
+	if e.Source != nil {
+		sourceFile = sql.NullString{String: e.Source.File, Valid: true}
+		sourceLine = sql.NullInt64{Int64: int64(e.Source.Line), Valid: true}
+		function = sql.NullString{String: e.Source.Function, Valid: true}
+	}
+
+	var stack sql.NullString
+	if len(e.Stack) > 0 {
+		data, err := json.Marshal(e.Stack)
+		if err != nil {
+			return 0, fmt.Errorf("encoding stack trace: %w", err)
+		}
+		stack = sql.NullString{String: string(data), Valid: true}
+	}
+
+	var fingerprint sql.NullString
+	if e.Fingerprint != "" {
+		fingerprint = sql.NullString{String: e.Fingerprint, Valid: true}
+	}
+
+	err := s.db.QueryRowContext(ctx, `INSERT INTO `+s.table+` (writer, generation, epoch_secs, nanos, level, content, source_file, source_line, function, stack, service, hostname, pid, fingerprint) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id`,
+		s.writer, s.generation, e.Time.Unix(), e.Time.Nanosecond(), int(e.Level), e.Content, sourceFile, sourceLine, function, stack,
+		s.identity.Service, s.identity.Hostname, s.identity.PID, fingerprint).Scan(&s.lastId)
+	if err != nil {
+		return 0, fmt.Errorf("inserting log record: %w", err)
+	}
+
+	s.sequence++
+
+	return s.sequence, nil
+}
+
+func (s *Store) RepeatLast(count int64, last time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`UPDATE `+s.table+` SET repeat_count = $1, last_epoch_secs = $2, last_nanos = $3 WHERE id = $4`,
+		count, last.Unix(), last.Nanosecond(), s.lastId)
+	if err != nil {
+		return fmt.Errorf("updating repeated log record: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) Rotate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	s.sequence = 0
+
+	// Discard the generations of the writer beyond the retention
+	_, err := s.db.Exec(`DELETE FROM `+s.table+` WHERE writer = $1 AND generation <= $2`, s.writer, s.generation-int64(s.numGenerations))
+	if err != nil {
+		return fmt.Errorf("deleting old generations: %w", err)
+	}
+
+	return nil
+}
+
+// CurrentName returns the table, the writer and its live generation, like
+// "sqlogger_entries/orders@host1#1234#3".
+func (s *Store) CurrentName() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.table + "/" + s.writer + "#" + strconv.FormatInt(s.generation, 10)
+}
+
+func (s *Store) Query(ctx context.Context, q sqlogger.Query) ([]sqlogger.Entry, error) {
+	if len(q.Attrs) > 0 {
+		return nil, fmt.Errorf("querying by attributes is not supported")
+	}
+
+	s.mu.Lock()
+	writer, generation := s.writer, s.generation
+	s.mu.Unlock()
+
+	where := []string{"writer = $1", "generation = $2"}
+	args := []any{writer, generation}
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if !q.Since.IsZero() {
+		secs, nanos := arg(q.Since.Unix()), arg(q.Since.Nanosecond())
+		where = append(where, "(epoch_secs > "+secs+" OR (epoch_secs = "+secs+" AND nanos >= "+nanos+"))")
+	}
+	if !q.Until.IsZero() {
+		secs, nanos := arg(q.Until.Unix()), arg(q.Until.Nanosecond())
+		where = append(where, "(epoch_secs < "+secs+" OR (epoch_secs = "+secs+" AND nanos < "+nanos+"))")
+	}
+	if q.MinLevel != nil {
+		where = append(where, "level >= "+arg(int(q.MinLevel.Level())))
+	}
+	if q.Contains != "" {
+		where = append(where, "strpos(content, "+arg(q.Contains)+") > 0")
+	}
+	if q.SourceFile != "" {
+		where = append(where, "source_file = "+arg(q.SourceFile))
+	}
+
+	stmt := "SELECT id, epoch_secs, nanos, level, content, repeat_count, last_epoch_secs, last_nanos, source_file, source_line, function, stack FROM " + s.table +
+		" WHERE " + strings.Join(where, " AND ") +
+		" ORDER BY epoch_secs, nanos, id"
+	if q.Limit > 0 {
+		stmt += " LIMIT " + strconv.Itoa(q.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying log entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []sqlogger.Entry
+	for rows.Next() {
+		var e sqlogger.Entry
+		var secs, nanos int64
+		var level int
+		var lastSecs, lastNanos, sourceLine sql.NullInt64
+		var sourceFile, function, stack sql.NullString
+		if err := rows.Scan(&e.ID, &secs, &nanos, &level, &e.Content, &e.RepeatCount, &lastSecs, &lastNanos, &sourceFile, &sourceLine, &function, &stack); err != nil {
+			return nil, fmt.Errorf("reading log entry: %w", err)
+		}
+		if e.Stack, err = sqlogger.DecodeStack(stack.String); err != nil {
+			return nil, err
+		}
+		e.Time = time.Unix(secs, nanos)
+		e.Level = slog.Level(level)
+		if lastSecs.Valid {
+			e.LastTime = time.Unix(lastSecs.Int64, lastNanos.Int64)
+		}
+		if sourceFile.Valid {
+			e.Source = &slog.Source{Function: function.String, File: sourceFile.String, Line: int(sourceLine.Int64)}
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// Healthy checks that the database is reachable and the table can be written.
+func (s *Store) Healthy(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("connecting to the database: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE `+s.table+` IN ROW EXCLUSIVE MODE NOWAIT`); err != nil {
+		return fmt.Errorf("table %s is not writable: %w", s.table, err)
+	}
+
+	return nil
+}
+
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Close()
+}
@@ -0,0 +1,91 @@
+// Package prom exports the internal metrics of a sqlogger handler to Prometheus.
+//
+//	prometheus.MustRegister(prom.NewCollector(logger))
+package prom
+
+import (
+	"github.com/hesusruiz/sqlogger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a prometheus.Collector reading the Stats of a handler on every scrape.
+type Collector struct {
+	h *sqlogger.SQLogger
+
+	entries        *prometheus.Desc
+	insertLatency  *prometheus.Desc
+	currentRows    *prometheus.Desc
+	rotations      *prometheus.Desc
+	errors         *prometheus.Desc
+	sinkErrors     *prometheus.Desc
+	deferred       *prometheus.Desc
+	dropped        *prometheus.Desc
+	consoleDropped *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector returns a collector for the metrics of h, named with the prefix "sqlogger_".
+func NewCollector(h *sqlogger.SQLogger) *Collector {
+	name := func(n string) string {
+		return prometheus.BuildFQName("sqlogger", "", n)
+	}
+
+	return &Collector{
+		h: h,
+		entries: prometheus.NewDesc(name("entries_total"),
+			"Entries written to the store.", []string{"level"}, nil),
+		insertLatency: prometheus.NewDesc(name("insert_latency_seconds"),
+			"Latency of the inserts in the store, with the quantiles of the recent ones.", nil, nil),
+		currentRows: prometheus.NewDesc(name("current_rows"),
+			"Entries in the live storage unit.", []string{"file"}, nil),
+		rotations: prometheus.NewDesc(name("rotations_total"),
+			"Rotations performed.", nil, nil),
+		errors: prometheus.NewDesc(name("insert_errors_total"),
+			"Failed inserts in the store.", nil, nil),
+		sinkErrors: prometheus.NewDesc(name("sink_errors_total"),
+			"Entries which could not be sent to a sink.", nil, nil),
+		deferred: prometheus.NewDesc(name("deferred_entries"),
+			"Entries waiting in the retry queue.", nil, nil),
+		dropped: prometheus.NewDesc(name("dropped_entries_total"),
+			"Entries lost because the retry queue was full.", nil, nil),
+		consoleDropped: prometheus.NewDesc(name("console_dropped_lines_total"),
+			"Console lines lost because the queue of the asynchronous console was full.", nil, nil),
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.entries
+	ch <- c.insertLatency
+	ch <- c.currentRows
+	ch <- c.rotations
+	ch <- c.errors
+	ch <- c.sinkErrors
+	ch <- c.deferred
+	ch <- c.dropped
+	ch <- c.consoleDropped
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.h.Stats()
+
+	for level, n := range stats.EntriesByLevel {
+		ch <- prometheus.MustNewConstMetric(c.entries, prometheus.CounterValue, float64(n), level)
+	}
+
+	latency := stats.InsertLatency
+	ch <- prometheus.MustNewConstSummary(c.insertLatency, latency.Count, latency.Sum.Seconds(), map[float64]float64{
+		0.5:  latency.P50.Seconds(),
+		0.9:  latency.P90.Seconds(),
+		0.99: latency.P99.Seconds(),
+		1:    latency.Max.Seconds(),
+	})
+
+	ch <- prometheus.MustNewConstMetric(c.currentRows, prometheus.GaugeValue, float64(stats.CurrentRows), stats.CurrentFile)
+	ch <- prometheus.MustNewConstMetric(c.rotations, prometheus.CounterValue, float64(stats.Rotations))
+	ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(stats.Errors))
+	ch <- prometheus.MustNewConstMetric(c.sinkErrors, prometheus.CounterValue, float64(stats.SinkErrors))
+	ch <- prometheus.MustNewConstMetric(c.deferred, prometheus.GaugeValue, float64(stats.Deferred))
+	ch <- prometheus.MustNewConstMetric(c.dropped, prometheus.CounterValue, float64(stats.Dropped))
+	ch <- prometheus.MustNewConstMetric(c.consoleDropped, prometheus.CounterValue, float64(stats.ConsoleDropped))
+}
@@ -0,0 +1,230 @@
+package sqlogger
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reader queries all the database files of a rotation set as a single log, in
+// chronological order, hiding the numbering of the files and the wrap-around of
+// the ring. Compressed files are decompressed transparently.
+type Reader struct {
+	store          *SQLiteStore
+	indexedColumns map[string]string
+
+	mu    sync.RWMutex
+	files []readerFile
+}
+
+type readerFile struct {
+	name    string
+	db      *sql.DB
+	close   func() error
+	first   time.Time
+	isEmpty bool
+}
+
+// hasColumn reports whether the entries table of the file has a column, which the
+// files written by older versions may lack.
+func (f readerFile) hasColumn(ctx context.Context, column string) (bool, error) {
+	var n int
+	err := f.db.QueryRowContext(ctx, "SELECT count(*) FROM pragma_table_info('entries') WHERE name = ?", column).Scan(&n)
+	return n > 0, err
+}
+
+// OpenSet opens for reading the rotation set of the default store in dir.
+// Use SQLiteStore.NewReader for encrypted files or other options of the store.
+func OpenSet(dir string) (*Reader, error) {
+	return NewSQLiteStore(&SQLiteOptions{Dir: dir}).NewReader()
+}
+
+// NewReader opens for reading the database files of the store, with its options.
+// The files are read as they are when the Reader is opened, except the live file,
+// which sees the entries written afterwards. Use Refresh to see the files created
+// since then.
+func (s *SQLiteStore) NewReader() (*Reader, error) {
+	if s.single() {
+		return nil, fmt.Errorf("a store without database files can not be read with a Reader")
+	}
+
+	indexedColumns, err := indexedColumns(s.indexedAttrs)
+	if err != nil {
+		return nil, err
+	}
+
+	rd := &Reader{store: s, indexedColumns: indexedColumns}
+	if err := rd.Refresh(); err != nil {
+		return nil, err
+	}
+
+	return rd, nil
+}
+
+// Refresh updates the files of the Reader after rotations, opening the files
+// created since it was opened and closing those which were deleted. On error, the
+// Reader keeps its previous files.
+func (rd *Reader) Refresh() error {
+	names, err := rd.store.setFiles()
+	if err != nil {
+		return err
+	}
+
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+
+	current := map[string]readerFile{}
+	for _, f := range rd.files {
+		current[f.name] = f
+	}
+
+	var files, opened []readerFile
+	fail := func(err error) error {
+		for _, f := range opened {
+			f.close()
+		}
+		return err
+	}
+
+	for _, name := range names {
+		f, ok := current[name]
+		if !ok {
+			db, closeFn, err := rd.store.openLogFile(name)
+			if err != nil {
+				return fail(err)
+			}
+			f = readerFile{name: name, db: db, close: closeFn}
+			opened = append(opened, f)
+		}
+
+		// The first entry changes when a file of the ring is recreated
+		var secs, nanos int64
+		err = f.db.QueryRow("SELECT epoch_secs, nanos FROM entries ORDER BY rowid LIMIT 1").Scan(&secs, &nanos)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			f.isEmpty = true
+		case err != nil:
+			return fail(fmt.Errorf("reading %s: %w", name, err))
+		default:
+			f.isEmpty = false
+			f.first = time.Unix(secs, nanos)
+		}
+
+		files = append(files, f)
+	}
+
+	// The files are ordered by their first entry, which is independent of their
+	// numbers and of their modification times
+	slices.SortStableFunc(files, func(a, b readerFile) int {
+		if a.isEmpty != b.isEmpty {
+			if a.isEmpty {
+				return 1
+			}
+			return -1
+		}
+		return a.first.Compare(b.first)
+	})
+
+	for _, f := range rd.files {
+		if !slices.ContainsFunc(files, func(g readerFile) bool { return g.name == f.name }) {
+			f.close()
+		}
+	}
+	rd.files = files
+
+	return nil
+}
+
+// setFiles returns the names of the files of the rotation set in the directory
+// of the store, plain or compressed.
+func (s *SQLiteStore) setFiles() ([]string, error) {
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range dirEntries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, s.basename+".") {
+			continue
+		}
+		base := strings.TrimSuffix(name, compressedExtension)
+		if !strings.HasSuffix(base, "."+logFileExtension) {
+			continue
+		}
+		// The compressed file is discarded if the plain one is also there
+		if base != name && slices.Contains(names, filepath.Join(s.dir, base)) {
+			continue
+		}
+		if base == name {
+			names = slices.DeleteFunc(names, func(n string) bool { return n == filepath.Join(s.dir, name+compressedExtension) })
+		}
+		names = append(names, filepath.Join(s.dir, name))
+	}
+
+	return names, nil
+}
+
+// Files returns the names of the database files, oldest first.
+func (rd *Reader) Files() []string {
+	rd.mu.RLock()
+	defer rd.mu.RUnlock()
+
+	names := make([]string, len(rd.files))
+	for i, f := range rd.files {
+		names[i] = f.name
+	}
+	return names
+}
+
+// Query returns the entries selected by q from all the files, in chronological
+// order. The File of each entry is set to the file containing it.
+func (rd *Reader) Query(ctx context.Context, q Query) ([]Entry, error) {
+	rd.mu.RLock()
+	defer rd.mu.RUnlock()
+
+	var entries []Entry
+	for _, f := range rd.files {
+		fileEntries, err := queryEntries(ctx, f.db, q, rd.indexedColumns)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.name, err)
+		}
+		for i := range fileEntries {
+			fileEntries[i].File = f.name
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	// Files may overlap in time if the clock went backwards
+	slices.SortStableFunc(entries, func(a, b Entry) int {
+		return cmp.Compare(a.Time.UnixNano(), b.Time.UnixNano())
+	})
+
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[:q.Limit]
+	}
+
+	return entries, nil
+}
+
+// Close closes all the files, removing the temporary copies of the compressed ones.
+func (rd *Reader) Close() error {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+
+	var errs []error
+	for _, f := range rd.files {
+		errs = append(errs, f.close())
+	}
+	rd.files = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,109 @@
+package sqlogger
+
+import (
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const defaultRedactionMask = "[REDACTED]"
+
+// Patterns of common personal data, for Redaction.Patterns.
+var (
+	EmailPattern      = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	CardNumberPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+)
+
+// Redaction masks sensitive values before they reach the console, the database
+// or the sinks.
+type Redaction struct {
+	// Keys are patterns of the attribute keys whose values are masked entirely,
+	// like "password" or "*_token", in the syntax of path.Match. They are matched
+	// case-insensitively against the key, without the groups.
+	Keys []string
+
+	// Patterns are masked wherever they appear in the message or in the string
+	// values of the attributes, like EmailPattern or CardNumberPattern.
+	Patterns []*regexp.Regexp
+
+	// Mask replaces the redacted values. If empty, "[REDACTED]" is used.
+	Mask string
+}
+
+// redactKey reports whether the value of the attribute with the key must be masked.
+func (rd *Redaction) redactKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range rd.Keys {
+		if ok, _ := path.Match(strings.ToLower(pattern), key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// redactString masks the patterns in s.
+func (rd *Redaction) redactString(s string) (string, bool) {
+	redacted := false
+	for _, re := range rd.Patterns {
+		if re.MatchString(s) {
+			s = re.ReplaceAllLiteralString(s, rd.mask())
+			redacted = true
+		}
+	}
+	return s, redacted
+}
+
+func (rd *Redaction) mask() string {
+	if rd.Mask == "" {
+		return defaultRedactionMask
+	}
+	return rd.Mask
+}
+
+// redactAttrs masks the values of the attributes, inside groups too.
+func (rd *Redaction) redactAttrs(attrs []slog.Attr) ([]slog.Attr, bool) {
+	var redacted bool
+	out := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		out[i] = a
+		a.Value = a.Value.Resolve()
+
+		switch {
+		case rd.redactKey(a.Key) && a.Value.Kind() != slog.KindGroup:
+			out[i] = slog.String(a.Key, rd.mask())
+			redacted = true
+		case a.Value.Kind() == slog.KindGroup:
+			if group, r := rd.redactAttrs(a.Value.Group()); r {
+				out[i] = slog.Attr{Key: a.Key, Value: slog.GroupValue(group...)}
+				redacted = true
+			}
+		case a.Value.Kind() == slog.KindString || a.Value.Kind() == slog.KindAny:
+			if s, r := rd.redactString(a.Value.String()); r {
+				out[i] = slog.String(a.Key, s)
+				redacted = true
+			}
+		}
+	}
+	return out, redacted
+}
+
+// redact returns the record with the sensitive values masked.
+func (rd *Redaction) redact(r slog.Record) slog.Record {
+	msg, redacted := rd.redactString(r.Message)
+
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	attrs, r2 := rd.redactAttrs(attrs)
+
+	if !redacted && !r2 {
+		return r
+	}
+
+	nr := slog.NewRecord(r.Time, r.Level, msg, r.PC)
+	nr.AddAttrs(attrs...)
+	return nr
+}
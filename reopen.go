@@ -0,0 +1,47 @@
+package sqlogger
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+)
+
+// Reopen seals the live file and starts a new one on demand, for external tools
+// like logrotate or operators, instead of waiting for the live file to fill up.
+// The sealed file is passed to Options.OnRotate, and can be moved away safely
+// once Reopen returns. It waits for a rotation in progress, like Rotate.
+func (h *SQLogger) Reopen() error {
+	return h.Rotate()
+}
+
+// ReopenOnSignal calls Reopen every time the process receives one of the signals,
+// usually syscall.SIGHUP as sent by logrotate:
+//
+//	stop := handler.ReopenOnSignal(syscall.SIGHUP)
+//	defer stop()
+//
+// The errors are logged with the default slog logger. The returned function stops
+// listening to the signals.
+func (h *SQLogger) ReopenOnSignal(sigs ...os.Signal) (stop func()) {
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, sigs...)
+
+	go func() {
+		for {
+			select {
+			case <-c:
+				if err := h.Reopen(); err != nil {
+					slog.Error("reopening log file", "error", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(c)
+		close(done)
+	}
+}
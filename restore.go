@@ -0,0 +1,119 @@
+package sqlogger
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// restoredPart starts the middle part of the names of the restored files, like
+// logs.restored-20240501T120000-3.sqlite, which the ring and the timestamped
+// files never use.
+const restoredPart = "restored-"
+
+// Basename returns the base name of the files of the rotation set, including the
+// instance, like "logs" or "logs-worker1".
+func (s *SQLiteStore) Basename() string {
+	return s.basename
+}
+
+// Restore adds a file archived from the rotation set of the store, plain or
+// compressed with zstd, to the directory of the store, so the Readers of the set
+// include its entries. name is the name of the file when it was sealed, like
+// logs.3.sqlite.zst, and sealed the time it was sealed, which names the restored
+// file, like logs.restored-20240501T120000-3.sqlite. The files of an encrypted
+// store are decrypted with the key of their original name.
+//
+// The restored files are read-only, and are not part of the ring or of the audit
+// chain, so the store never deletes them: remove them when they are no longer
+// needed. A file already restored is not restored again. Restore returns the path
+// of the restored file.
+func (s *SQLiteStore) Restore(ctx context.Context, src string, name string, sealed time.Time) (string, error) {
+	base := strings.TrimSuffix(filepath.Base(name), compressedExtension)
+	parts := strings.Split(base, ".")
+	if len(parts) != 3 || parts[0] != s.basename || parts[2] != logFileExtension || strings.HasPrefix(parts[1], restoredPart) {
+		return "", fmt.Errorf("%s is not a file of the %s set", name, s.basename)
+	}
+
+	dst := filepath.Join(s.dir, fmt.Sprintf("%s.%s%s-%s.%s", s.basename, restoredPart, sealed.UTC().Format(timestampLayout), parts[1], logFileExtension))
+	if _, err := os.Stat(dst); err == nil {
+		return dst, nil
+	}
+
+	if strings.HasSuffix(name, compressedExtension) {
+		tmp, err := decompressFile(src)
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(tmp)
+		src = tmp
+	}
+
+	// The copy is made under a temporary name, so the Readers never see it partially written
+	tmp := dst + ".tmp"
+	os.Remove(tmp)
+	if err := s.copyDecrypted(ctx, src, filepath.Join(s.dir, base), tmp); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("restoring %s: %w", name, err)
+	}
+	if err := os.Chmod(tmp, 0o444); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	return dst, nil
+}
+
+// copyDecrypted copies the database src, encrypted with the key of the file
+// keyName if the store is encrypted, to the plain database dst with VACUUM INTO
+// or, for encrypted files, sqlcipher_export.
+func (s *SQLiteStore) copyDecrypted(ctx context.Context, src string, keyName string, dst string) error {
+	db, err := s.openKeyedDB(src, keyName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if s.encryptionKey == nil {
+		_, err := db.ExecContext(ctx, "VACUUM INTO ?", dst)
+		return err
+	}
+
+	// The attached database must be in the same connection as the export
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS plain KEY ''", dst); err != nil {
+		return err
+	}
+	_, err = conn.ExecContext(ctx, "SELECT sqlcipher_export('plain')")
+	_, detachErr := conn.ExecContext(ctx, "DETACH DATABASE plain")
+	return errors.Join(err, detachErr)
+}
+
+// isRestored reports whether a file of the set was added by Restore.
+func isRestored(name string) bool {
+	parts := strings.Split(filepath.Base(name), ".")
+	return len(parts) == 3 && strings.HasPrefix(parts[1], restoredPart)
+}
+
+// openRestored opens a restored file, which is never encrypted.
+func openRestored(name string) (*sql.DB, func() error, error) {
+	db, err := sql.Open("sqlite3", name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, db.Close, nil
+}
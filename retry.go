@@ -0,0 +1,134 @@
+package sqlogger
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+const defaultRetryQueueSize = 1000
+
+// retryQueue keeps the entries which could not be inserted in the store, to retry
+// them before the next insert. It is bounded, dropping the oldest entries when full.
+type retryQueue struct {
+	mu       ctxMutex
+	size     int
+	entries  []Entry
+	dropped  atomic.Uint64
+	timedOut atomic.Uint64
+}
+
+// WriteFailures reports the entries which could not be written to the store.
+type WriteFailures struct {
+	// Deferred is the number of entries waiting in the retry queue.
+	Deferred int
+
+	// Dropped is the number of entries lost because the retry queue was full.
+	Dropped uint64
+
+	// TimedOut is the number of inserts abandoned because the context of the record
+	// was done or Options.WriteTimeout was exceeded.
+	TimedOut uint64
+}
+
+// WriteFailures returns the counters of the entries which could not be written to
+// the store. Those entries were written to Options.Fallback anyway.
+func (h *SQLogger) WriteFailures() WriteFailures {
+	q := h.retry
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return WriteFailures{
+		Deferred: len(q.entries),
+		Dropped:  q.dropped.Load(),
+		TimedOut: q.timedOut.Load(),
+	}
+}
+
+// insert writes an entry to the store, retrying first the deferred entries to keep
+// the order. If the entry can not be inserted it is written to the fallback writer
+// and deferred, also when ctx is done before it is inserted. If ctx is done while
+// waiting for the queue, the entry is written to the fallback writer and dropped.
+// It returns the number of entries inserted, including the deferred ones.
+func (h *SQLogger) insert(ctx context.Context, e Entry) (int, error) {
+	q := h.retry
+	if err := q.mu.LockContext(ctx); err != nil {
+		q.timedOut.Add(1)
+		q.dropped.Add(1)
+		h.opts.Fallback.Write([]byte(e.Content))
+		return 0, fmt.Errorf("dropping log record: %w", err)
+	}
+	defer q.mu.Unlock()
+
+	var inserted int
+	var err error
+
+	for len(q.entries) > 0 {
+		if _, err = h.storeInsert(ctx, q.entries[0]); err != nil {
+			break
+		}
+		q.entries[0] = Entry{}
+		q.entries = q.entries[1:]
+		inserted++
+	}
+
+	if err == nil {
+		if _, err = h.storeInsert(ctx, e); err == nil {
+			return inserted + 1, nil
+		}
+	}
+
+	if ctx.Err() != nil {
+		q.timedOut.Add(1)
+	}
+
+	// The entry is not lost even if it can not be deferred
+	h.opts.Fallback.Write([]byte(e.Content))
+
+	if q.size <= 0 {
+		q.dropped.Add(1)
+		return inserted, fmt.Errorf("dropping log record: %w", err)
+	}
+
+	if len(q.entries) >= q.size {
+		q.entries[0] = Entry{}
+		q.entries = q.entries[1:]
+		q.dropped.Add(1)
+	}
+	q.entries = append(q.entries, e)
+
+	return inserted, fmt.Errorf("deferring log record: %w", err)
+}
+
+// storeInsert inserts an entry in the store, recording the metrics of the insert.
+// The context is honored if the store implements ContextInserter.
+func (h *SQLogger) storeInsert(ctx context.Context, e Entry) (int64, error) {
+	start := time.Now()
+	var id int64
+	var err error
+	if s, ok := h.store.(ContextInserter); ok {
+		id, err = s.InsertContext(ctx, e)
+	} else {
+		id, err = h.store.Insert(e)
+	}
+	h.metrics.observeInsert(e.Level, time.Since(start), err)
+	return id, err
+}
+
+// flushRetries tries to insert the deferred entries, returning an error if some remain.
+func (h *SQLogger) flushRetries() error {
+	q := h.retry
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.entries) > 0 {
+		if _, err := h.storeInsert(context.Background(), q.entries[0]); err != nil {
+			return fmt.Errorf("%d deferred log records not written: %w", len(q.entries), err)
+		}
+		q.entries[0] = Entry{}
+		q.entries = q.entries[1:]
+	}
+
+	return nil
+}
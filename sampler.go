@@ -0,0 +1,69 @@
+package sqlogger
+
+import (
+	"hash/fnv"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// SampledDroppedKey is the key of the attribute added to a sampled record with the
+// number of records with the same level and message suppressed before it.
+const SampledDroppedKey = "sampled_dropped"
+
+// samplerCounters is the number of counters of a Sampler. Records are assigned to a
+// counter by the hash of their level and message, so memory is bounded no matter how
+// many different messages are logged, at the cost of rare collisions.
+const samplerCounters = 4096
+
+// Sampler limits the volume of repetitive records: in every tick, the first records
+// with a given level and message are logged and then only one in every thereafter.
+// It is applied before the console and the database, so high-frequency loops do
+// not flood the database or trigger premature rotation.
+type Sampler struct {
+	tick       time.Duration
+	first      int64
+	thereafter int64
+	counters   [samplerCounters]samplerCounter
+}
+
+type samplerCounter struct {
+	resetAt atomic.Int64
+	count   atomic.Int64
+	dropped atomic.Int64
+}
+
+// NewSampler returns a Sampler logging the first records with the same level and
+// message in every tick, and then one in every thereafter. If thereafter is zero,
+// all the records after the first ones are dropped until the next tick.
+func NewSampler(tick time.Duration, first int, thereafter int) *Sampler {
+	return &Sampler{
+		tick:       tick,
+		first:      int64(first),
+		thereafter: int64(thereafter),
+	}
+}
+
+// sample decides if the record must be logged, returning the number of records
+// suppressed since the last one logged with the same level and message.
+func (s *Sampler) sample(r *slog.Record) (bool, int64) {
+	hasher := fnv.New32a()
+	hasher.Write([]byte{byte(r.Level)})
+	hasher.Write([]byte(r.Message))
+	c := &s.counters[hasher.Sum32()%samplerCounters]
+
+	// Start a new tick if the current one has expired
+	now := r.Time.UnixNano()
+	resetAt := c.resetAt.Load()
+	if now > resetAt && c.resetAt.CompareAndSwap(resetAt, now+int64(s.tick)) {
+		c.count.Store(0)
+	}
+
+	n := c.count.Add(1)
+	if n <= s.first || (s.thereafter > 0 && (n-s.first)%s.thereafter == 0) {
+		return true, c.dropped.Swap(0)
+	}
+
+	c.dropped.Add(1)
+	return false, 0
+}
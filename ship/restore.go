@@ -0,0 +1,116 @@
+package ship
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hesusruiz/sqlogger"
+)
+
+// Downloader reads the objects stored by an Uploader, to restore them.
+type Downloader interface {
+	// List returns the keys of the objects starting with prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+
+	// Download writes the contents of the object with the given key to the file at path.
+	Download(ctx context.Context, key string, path string) error
+}
+
+// RestoreOptions selects the archived files restored by Restore.
+type RestoreOptions struct {
+	// Prefix is the prefix of the object keys, as in Options.
+	Prefix string
+
+	// Since and Until select the files with entries in the time range. A zero
+	// time means no limit on that side.
+	Since time.Time
+	Until time.Time
+}
+
+// archived is a file shipped by a Shipper.
+type archived struct {
+	key    string
+	name   string
+	sealed time.Time
+}
+
+// Restore downloads the files of the rotation set of store shipped by a Shipper
+// with entries in the time range, and adds them to the directory of the store
+// with SQLiteStore.Restore, so the Readers of the set include them:
+//
+//	restored, err := ship.Restore(ctx, downloader, store, &ship.RestoreOptions{
+//		Prefix: "orders/",
+//		Since:  time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+//		Until:  time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+//	})
+//
+// A file holds the entries logged from the time the previous file of the set was
+// sealed until it was sealed itself. It returns the paths of the restored files,
+// oldest first, also on error.
+func Restore(ctx context.Context, d Downloader, store *sqlogger.SQLiteStore, opts *RestoreOptions) ([]string, error) {
+	var o RestoreOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	keys, err := d.List(ctx, o.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []archived
+	for _, key := range keys {
+		stamp, name, ok := strings.Cut(strings.TrimPrefix(key, o.Prefix), "-")
+		if !ok || !strings.HasPrefix(name, store.Basename()+".") {
+			continue
+		}
+		sealed, err := time.Parse(keyTimeLayout, stamp)
+		if err != nil {
+			continue
+		}
+		files = append(files, archived{key: key, name: name, sealed: sealed})
+	}
+	slices.SortFunc(files, func(a, b archived) int {
+		return a.sealed.Compare(b.sealed)
+	})
+
+	// The keys have the times in seconds, so a file sealed in the second of Since
+	// may have entries after it
+	since := o.Since.Truncate(time.Second)
+
+	var restored []string
+	for i, f := range files {
+		if f.sealed.Before(since) {
+			continue
+		}
+		if !o.Until.IsZero() && i > 0 && !files[i-1].sealed.Before(o.Until) {
+			break
+		}
+
+		path, err := restoreFile(ctx, d, store, f)
+		if err != nil {
+			return restored, err
+		}
+		restored = append(restored, path)
+	}
+
+	return restored, nil
+}
+
+// restoreFile downloads an archived file to a temporary file and restores it.
+func restoreFile(ctx context.Context, d Downloader, store *sqlogger.SQLiteStore, f archived) (string, error) {
+	tmp, err := os.CreateTemp("", "sqlogger-restore-*")
+	if err != nil {
+		return "", err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := d.Download(ctx, f.key, tmp.Name()); err != nil {
+		return "", err
+	}
+	return store.Restore(ctx, tmp.Name(), f.name, f.sealed)
+}
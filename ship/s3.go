@@ -0,0 +1,146 @@
+package ship
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Config configures an uploader for S3 or any service compatible with its API,
+// like MinIO, or Google Cloud Storage with HMAC keys.
+type S3Config struct {
+	// Bucket is the destination bucket. It is required.
+	Bucket string
+
+	// Endpoint is the URL of the service, for example "http://localhost:9000" for
+	// MinIO or "https://storage.googleapis.com" for GCS. If empty, AWS S3 is used.
+	Endpoint string
+
+	// Region of the bucket. If empty, it is taken from the AWS environment.
+	Region string
+
+	// AccessKey and SecretKey are static credentials. If empty, the default AWS
+	// credential chain is used (environment, shared config, instance role...).
+	AccessKey string
+	SecretKey string
+
+	// UsePathStyle addresses the bucket in the path instead of the host name,
+	// which is needed by MinIO and most self-hosted services.
+	UsePathStyle bool
+}
+
+// S3Uploader is an Uploader storing the files in an S3 bucket, and the Downloader
+// to restore them.
+type S3Uploader struct {
+	client *s3.Client
+	bucket string
+}
+
+var _ Uploader = (*S3Uploader)(nil)
+var _ Downloader = (*S3Uploader)(nil)
+
+// NewS3 returns an uploader for the S3-compatible service described by cfg.
+func NewS3(ctx context.Context, cfg *S3Config) (*S3Uploader, error) {
+	if cfg == nil || cfg.Bucket == "" {
+		return nil, fmt.Errorf("the bucket is required")
+	}
+
+	var loadOpts []func(*config.LoadOptions) error
+	if cfg.Region != "" {
+		loadOpts = append(loadOpts, config.WithRegion(cfg.Region))
+	}
+	if cfg.AccessKey != "" {
+		loadOpts = append(loadOpts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, "")))
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("loading S3 configuration: %w", err)
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+		o.UsePathStyle = cfg.UsePathStyle
+	})
+
+	return NewS3Uploader(client, cfg.Bucket), nil
+}
+
+// NewS3Uploader returns an uploader using an already configured client.
+func NewS3Uploader(client *s3.Client, bucket string) *S3Uploader {
+	return &S3Uploader{client: client, bucket: bucket}
+}
+
+func (u *S3Uploader) Upload(ctx context.Context, key string, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	_, err = u.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(u.bucket),
+		Key:           aws.String(key),
+		Body:          f,
+		ContentLength: aws.Int64(info.Size()),
+	})
+	if err != nil {
+		return fmt.Errorf("uploading %s to s3://%s/%s: %w", path, u.bucket, key, err)
+	}
+
+	return nil
+}
+
+func (u *S3Uploader) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	pages := s3.NewListObjectsV2Paginator(u.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(u.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing s3://%s/%s: %w", u.bucket, prefix, err)
+		}
+		for _, obj := range page.Contents {
+			keys = append(keys, aws.ToString(obj.Key))
+		}
+	}
+	return keys, nil
+}
+
+func (u *S3Uploader) Download(ctx context.Context, key string, path string) error {
+	out, err := u.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(u.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("downloading s3://%s/%s: %w", u.bucket, key, err)
+	}
+	defer out.Body.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, out.Body)
+	if err = errors.Join(err, f.Close()); err != nil {
+		return fmt.Errorf("downloading s3://%s/%s: %w", u.bucket, key, err)
+	}
+	return nil
+}
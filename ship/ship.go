@@ -0,0 +1,186 @@
+// Package ship uploads the log files sealed by rotation to object storage, so
+// long-term retention does not depend on the local disk.
+//
+// A Shipper is driven by the rotation hook of the handler:
+//
+//	uploader, err := ship.NewS3(ctx, &ship.S3Config{Bucket: "logs"})
+//	shipper := ship.New(uploader, &ship.Options{Prefix: "orders/"})
+//	defer shipper.Close(context.Background())
+//
+//	logger, err := sqlogger.NewSQLogger(&sqlogger.Options{OnRotate: shipper.OnRotate})
+package ship
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const defaultRetries = 5
+const defaultBackoff = time.Second
+const defaultQueueSize = 64
+
+// The layout of the time a file was sealed in its object key
+const keyTimeLayout = "20060102T150405Z"
+
+// Uploader stores objects in an object storage service.
+type Uploader interface {
+	// Upload stores the contents of the file at path under the given key.
+	Upload(ctx context.Context, key string, path string) error
+}
+
+// Options configures a Shipper.
+type Options struct {
+	// Prefix is prepended to the object keys, for example "service-a/".
+	Prefix string
+
+	// Retries is the number of times a failed upload is retried.
+	// If zero, 5 retries are done. Set it to a negative number to disable retries.
+	Retries int
+
+	// Backoff is the wait before the first retry, doubled in every retry.
+	// If zero, one second is used.
+	Backoff time.Duration
+
+	// QueueSize is the number of sealed files which can be waiting for upload.
+	// When the queue is full new files are not shipped. If zero, 64 is used.
+	QueueSize int
+
+	// OnError, if not nil, is called when a file could not be uploaded after all
+	// the retries. By default the error is logged with the default slog logger.
+	OnError func(file string, err error)
+}
+
+// Shipper uploads sealed log files in the background.
+type Shipper struct {
+	uploader Uploader
+	opts     Options
+
+	mu     sync.Mutex
+	closed bool
+	queue  chan string
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New returns a Shipper uploading with the given Uploader, and starts its
+// background worker. Call Close to wait for the pending uploads.
+func New(uploader Uploader, opts *Options) *Shipper {
+	s := &Shipper{uploader: uploader}
+
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.Retries == 0 {
+		s.opts.Retries = defaultRetries
+	}
+	if s.opts.Backoff == 0 {
+		s.opts.Backoff = defaultBackoff
+	}
+	if s.opts.QueueSize == 0 {
+		s.opts.QueueSize = defaultQueueSize
+	}
+	if s.opts.OnError == nil {
+		s.opts.OnError = func(file string, err error) {
+			slog.Error("shipping log file", "file", file, "error", err)
+		}
+	}
+
+	s.queue = make(chan string, s.opts.QueueSize)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	s.wg.Add(1)
+	go s.run()
+
+	return s
+}
+
+// OnRotate queues the closed file for upload. Its signature matches
+// sqlogger.Options.OnRotate, so it can be used directly as the rotation hook.
+func (s *Shipper) OnRotate(closedFile string, newFile string) {
+	s.Ship(closedFile)
+}
+
+// Ship queues a file for upload, without blocking.
+func (s *Shipper) Ship(file string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		s.opts.OnError(file, fmt.Errorf("shipper is closed"))
+		return
+	}
+
+	select {
+	case s.queue <- file:
+	default:
+		s.opts.OnError(file, fmt.Errorf("upload queue is full"))
+	}
+}
+
+// Close waits for the queued files to be uploaded and stops the worker.
+// Files waiting for a retry are abandoned if ctx is done before.
+func (s *Shipper) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (s *Shipper) run() {
+	defer s.wg.Done()
+	for file := range s.queue {
+		if err := s.upload(file); err != nil {
+			s.opts.OnError(file, err)
+		}
+	}
+}
+
+// upload ships a file, retrying with exponential backoff.
+func (s *Shipper) upload(file string) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+
+	// Files in the rotation reuse their names, so the object key is made unique
+	// with the time the file was sealed
+	key := s.opts.Prefix + info.ModTime().UTC().Format(keyTimeLayout) + "-" + filepath.Base(file)
+
+	backoff := s.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		err = s.uploader.Upload(s.ctx, key, file)
+		if err == nil || attempt >= s.opts.Retries {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-s.ctx.Done():
+			return err
+		}
+	}
+}
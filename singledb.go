@@ -0,0 +1,103 @@
+package sqlogger
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// MemoryDir is the directory of a SQLiteStore keeping the entries in an in-memory
+// database instead of files, for tests and short-lived tools.
+const MemoryDir = ":memory:"
+
+// NewSQLiteStoreWithDB returns a store writing the entries to the given database
+// instead of a ring of files, so callers can use their own connection settings.
+// Rotation recreates the entries table in place, and the options which need files,
+// like encryption, compression or timestamped naming, are not supported.
+// The entries table of db is recreated when the store is opened, and db is not
+// closed when the store is closed.
+func NewSQLiteStoreWithDB(db *sql.DB, opts *SQLiteOptions) *SQLiteStore {
+	s := NewSQLiteStore(opts)
+	s.singleDB = db
+	return s
+}
+
+// single reports whether the store writes to a single database instead of files.
+func (s *SQLiteStore) single() bool {
+	return s.singleDB != nil || s.dir == MemoryDir
+}
+
+// singleName returns the name of the live unit of a single database, which
+// changes with every rotation.
+func (s *SQLiteStore) singleName() string {
+	base := "db"
+	if s.dir == MemoryDir {
+		base = MemoryDir
+	}
+	return fmt.Sprintf("%s#%d", base, s.currentLogId)
+}
+
+// openSingle opens the store on a single database, with the lock held.
+func (s *SQLiteStore) openSingle() error {
+	if s.encryptionKey != nil || s.compressRotated || s.naming != ByNumber || s.instance != "" {
+		return fmt.Errorf("encryption, compression, naming and instance options require database files")
+	}
+
+	db := s.singleDB
+	if db == nil {
+		var err error
+		if db, err = sql.Open("sqlite3", MemoryDir); err != nil {
+			return err
+		}
+		// Every connection to :memory: is a different database
+		db.SetMaxOpenConns(1)
+		s.ownDB = true
+	}
+
+	if err := s.createSchema(db, openLogSQL); err != nil {
+		if s.ownDB {
+			db.Close()
+		}
+		return err
+	}
+
+	s.singleDB = db
+	s.db = db
+	s.currentName = s.singleName()
+
+	return nil
+}
+
+// rotateSingle starts a new live unit on a single database, discarding the entries.
+func (s *SQLiteStore) rotateSingle() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.createSchema(s.db, resetLogSQL); err != nil {
+		return err
+	}
+
+	s.currentLogId++
+	s.currentName = s.singleName()
+	s.lastRowId = 0
+
+	return nil
+}
+
+// closeSingle closes the database if it was opened by the store.
+func (s *SQLiteStore) closeSingle() error {
+	if !s.ownDB {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// verifySingle checks the hash chain of the live unit of a single database.
+func (s *SQLiteStore) verifySingle(ctx context.Context) error {
+	s.mu.Lock()
+	db, name := s.db, s.currentName
+	s.mu.Unlock()
+
+	_, err := verifyDB(ctx, db, name, nil, true)
+	return err
+}
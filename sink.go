@@ -0,0 +1,48 @@
+package sqlogger
+
+import "log/slog"
+
+// Sink receives a copy of every entry, in addition to the console and the Store,
+// to forward it to another logging system. See Options.Sinks.
+type Sink interface {
+	// Send forwards an entry. It is called synchronously from Handle.
+	Send(e *Entry) error
+
+	// Close flushes and releases the resources of the sink.
+	Close() error
+}
+
+// Syslog severities, as defined in RFC 5424.
+const (
+	severityCritical = 2
+	severityError    = 3
+	severityWarning  = 4
+	severityInfo     = 6
+	severityDebug    = 7
+)
+
+// severity maps a slog level to the syslog severity, with the levels above
+// ERROR (like FATAL) mapped to critical.
+func severity(level slog.Level) int {
+	switch {
+	case level < slog.LevelInfo:
+		return severityDebug
+	case level < slog.LevelWarn:
+		return severityInfo
+	case level < slog.LevelError:
+		return severityWarning
+	case level == slog.LevelError:
+		return severityError
+	default:
+		return severityCritical
+	}
+}
+
+// sinkText renders the message and the attributes of an entry in a single line,
+// for destinations which record the time and severity by themselves.
+func sinkText(e *Entry) string {
+	if len(e.Attrs) == 0 {
+		return e.Message
+	}
+	return e.Message + " " + attrsText(e.Attrs)
+}
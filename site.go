@@ -0,0 +1,62 @@
+package sqlogger
+
+import (
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// The log calls, by program counter. They are bounded by the number of log calls
+// in the program.
+var sites sync.Map
+
+// site is a log call, with its location relative to a working directory.
+type site struct {
+	frame runtime.Frame
+	cwd   string
+
+	// The location as printed, like "cmd/server/main.go:42", and the file as stored
+	location string
+	file     string
+
+	// The fingerprint of the last message logged from the site, as most sites
+	// log a constant message
+	last atomic.Pointer[messageFingerprint]
+}
+
+type messageFingerprint struct {
+	message     string
+	fingerprint string
+}
+
+// callSite returns the log call at pc, with its location relative to the working
+// directory of the handler.
+func (h *SQLogger) callSite(pc uintptr) *site {
+	if s, ok := sites.Load(pc); ok && s.(*site).cwd == h.cwd {
+		return s.(*site)
+	}
+
+	f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	file := h.relativeFile(f.File)
+	s := &site{
+		frame:    f,
+		cwd:      h.cwd,
+		location: file + ":" + strconv.Itoa(f.Line),
+		file:     filepath.ToSlash(file),
+	}
+	sites.Store(pc, s)
+	return s
+}
+
+// fingerprint returns the fingerprint of the entry logged from the site with the
+// message msg.
+func (s *site) fingerprint(msg string) string {
+	if last := s.last.Load(); last != nil && last.message == msg {
+		return last.fingerprint
+	}
+	fp := fingerprint(s.frame.Function, msg)
+	s.last.Store(&messageFingerprint{msg, fp})
+	return fp
+}
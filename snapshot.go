@@ -0,0 +1,55 @@
+package sqlogger
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Snapshotter is implemented by stores which can copy their live storage unit
+// while the entries keep being inserted.
+type Snapshotter interface {
+	Snapshot(ctx context.Context, dest string) error
+}
+
+// Snapshot writes a consistent copy of the live database to the file dest, which
+// must not exist, for offline analysis of a running service. The entries waiting
+// for a retry are written first. Logging continues while the copy is made.
+// The store must implement Snapshotter, like the default store.
+func (h *SQLogger) Snapshot(ctx context.Context, dest string) error {
+	s, ok := h.store.(Snapshotter)
+	if !ok {
+		return fmt.Errorf("the store does not support snapshots")
+	}
+	if err := h.flushRetries(); err != nil {
+		return err
+	}
+	return s.Snapshot(ctx, dest)
+}
+
+// Snapshot copies the live database to dest with VACUUM INTO, which reads it in a
+// single transaction, so the copy is consistent and compacted. The copy of an
+// encrypted database is encrypted with the same key.
+func (s *SQLiteStore) Snapshot(ctx context.Context, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("snapshot file %s already exists", dest)
+	}
+
+	s.mu.Lock()
+	db, name := s.db, s.currentName
+	s.mu.Unlock()
+
+	// The connections of an append-only store can not run VACUUM
+	if s.appendOnly {
+		var err error
+		if db, err = s.openDB(name); err != nil {
+			return err
+		}
+		defer db.Close()
+	}
+
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", dest); err != nil {
+		return fmt.Errorf("copying %s to %s: %w", name, dest, err)
+	}
+	return nil
+}
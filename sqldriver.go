@@ -0,0 +1,309 @@
+package sqlogger
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// SQLLogOptions configures WrapDriver.
+type SQLLogOptions struct {
+	// Level is the level of the entries of the statements. If nil, DEBUG is used.
+	Level slog.Leveler
+
+	// SlowThreshold, if positive, logs the statements taking longer as WARN.
+	SlowThreshold time.Duration
+
+	// HideArgs replaces the values of the arguments by "[REDACTED]". Otherwise
+	// they are logged as the group sql.args, with keys like "1" or the name of the
+	// argument, so Options.Redaction applies to them.
+	HideArgs bool
+}
+
+// WrapDriver returns a driver logging every statement executed through d with the
+// attributes sql.query, sql.args, sql.rows_affected and sql.duration, so slow
+// queries can be correlated with the other entries. Failed statements are logged
+// as ERROR with the error. Register it with a name of its own:
+//
+//	sql.Register("sqlite3-logged", sqlogger.WrapDriver(&sqlite3.SQLiteDriver{}, logger, nil))
+//	db, err := sql.Open("sqlite3-logged", "app.db")
+//
+// The handler of logger must not write to a database opened with the wrapped driver.
+func WrapDriver(d driver.Driver, logger *slog.Logger, opts *SQLLogOptions) driver.Driver {
+	sl := &sqlLogger{logger: logger}
+	if opts != nil {
+		sl.opts = *opts
+	}
+	if sl.opts.Level == nil {
+		sl.opts.Level = slog.LevelDebug
+	}
+
+	if dc, ok := d.(driver.DriverContext); ok {
+		return &loggedDriverContext{loggedDriver{d, sl}, dc}
+	}
+	return &loggedDriver{d, sl}
+}
+
+// sqlLogger logs the statements of the connections of a wrapped driver.
+type sqlLogger struct {
+	logger *slog.Logger
+	opts   SQLLogOptions
+}
+
+// log logs a statement which started at start. Statements which the driver asks to
+// run in another way are not logged, as they are logged when run again.
+func (sl *sqlLogger) log(ctx context.Context, msg string, query string, args []driver.NamedValue, result driver.Result, start time.Time, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+
+	duration := time.Since(start)
+	level := sl.opts.Level.Level()
+	if sl.opts.SlowThreshold > 0 && duration > sl.opts.SlowThreshold {
+		level = slog.LevelWarn
+	}
+	if err != nil {
+		level = slog.LevelError
+	}
+
+	if !sl.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []any{
+		slog.String("query", query),
+		slog.Duration("duration", duration),
+	}
+	if len(args) > 0 {
+		attrs = append(attrs, slog.Group("args", sl.args(args)...))
+	}
+	if result != nil {
+		if n, err := result.RowsAffected(); err == nil {
+			attrs = append(attrs, slog.Int64("rows_affected", n))
+		}
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+
+	sl.logger.LogAttrs(ctx, level, msg, slog.Group("sql", attrs...))
+}
+
+// args returns the attributes of the arguments of a statement.
+func (sl *sqlLogger) args(args []driver.NamedValue) []any {
+	attrs := make([]any, 0, len(args))
+	for _, a := range args {
+		key := a.Name
+		if key == "" {
+			key = strconv.Itoa(a.Ordinal)
+		}
+		var value any = a.Value
+		if sl.opts.HideArgs {
+			value = defaultRedactionMask
+		} else if b, ok := value.([]byte); ok {
+			value = "[" + strconv.Itoa(len(b)) + " bytes]"
+		}
+		attrs = append(attrs, slog.Any(key, value))
+	}
+	return attrs
+}
+
+type loggedDriver struct {
+	driver.Driver
+	sl *sqlLogger
+}
+
+func (d *loggedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &loggedConn{c, d.sl}, nil
+}
+
+type loggedDriverContext struct {
+	loggedDriver
+	dc driver.DriverContext
+}
+
+func (d *loggedDriverContext) OpenConnector(name string) (driver.Connector, error) {
+	c, err := d.dc.OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return &loggedConnector{c, &d.loggedDriver}, nil
+}
+
+type loggedConnector struct {
+	driver.Connector
+	d *loggedDriver
+}
+
+func (c *loggedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &loggedConn{conn, c.d.sl}, nil
+}
+
+func (c *loggedConnector) Driver() driver.Driver {
+	return c.d
+}
+
+// loggedConn passes the optional interfaces of the connection through, returning
+// driver.ErrSkip for those it does not implement so database/sql falls back.
+type loggedConn struct {
+	driver.Conn
+	sl *sqlLogger
+}
+
+func (c *loggedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *loggedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var s driver.Stmt
+	var err error
+	if pc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		s, err = pc.PrepareContext(ctx, query)
+	} else {
+		s, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &loggedStmt{s, query, c.sl}, nil
+}
+
+func (c *loggedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bt, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return bt.BeginTx(ctx, opts)
+	}
+
+	// Like database/sql, the options which Begin can not honor are rejected
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("sql: driver does not support non-default isolation level")
+	}
+	if opts.ReadOnly {
+		return nil, errors.New("sql: driver does not support read-only transactions")
+	}
+	return c.Conn.Begin()
+}
+
+func (c *loggedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := ec.ExecContext(ctx, query, args)
+	c.sl.log(ctx, "sql exec", query, args, result, start, err)
+	return result, err
+}
+
+func (c *loggedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := qc.QueryContext(ctx, query, args)
+	c.sl.log(ctx, "sql query", query, args, nil, start, err)
+	return rows, err
+}
+
+func (c *loggedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *loggedConn) ResetSession(ctx context.Context) error {
+	if sr, ok := c.Conn.(driver.SessionResetter); ok {
+		return sr.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *loggedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *loggedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type loggedStmt struct {
+	driver.Stmt
+	query string
+	sl    *sqlLogger
+}
+
+func (s *loggedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *loggedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *loggedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var result driver.Result
+	var err error
+	if ec, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = ec.ExecContext(ctx, args)
+	} else {
+		result, err = s.Stmt.Exec(values(args))
+	}
+	s.sl.log(ctx, "sql exec", s.query, args, result, start, err)
+	return result, err
+}
+
+func (s *loggedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if qc, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = qc.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(values(args))
+	}
+	s.sl.log(ctx, "sql query", s.query, args, nil, start, err)
+	return rows, err
+}
+
+func (s *loggedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	vs := make([]driver.Value, len(args))
+	for i, a := range args {
+		vs[i] = a.Value
+	}
+	return vs
+}
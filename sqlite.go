@@ -0,0 +1,889 @@
+package sqlogger
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+const logFileBasename = "logs"
+const logFileExtension = "sqlite"
+
+// The time SQLite waits for a locked database, as set by the driver and the schema.
+const defaultBusyTimeout = 5 * time.Second
+
+const openLogSQL = `
+PRAGMA journal_mode = WAL;
+PRAGMA synchronous = NORMAL;
+PRAGMA busy_timeout = 5000;
+
+DROP TABLE IF EXISTS entries;
+
+CREATE TABLE IF NOT EXISTS entries (
+  epoch_secs LONG,
+  nanos INTEGER,
+  level INTEGER,
+  content BLOB,
+  repeat_count INTEGER NOT NULL DEFAULT 1,
+  last_epoch_secs LONG,
+  last_nanos INTEGER,
+  source_file TEXT,
+  source_line INTEGER,
+  function TEXT,
+  stack TEXT,
+  fingerprint TEXT
+);
+`
+const resetLogSQL = `
+PRAGMA journal_mode = WAL;
+PRAGMA synchronous = NORMAL;
+PRAGMA busy_timeout = 5000;
+
+DROP TABLE IF EXISTS entries;
+
+CREATE TABLE IF NOT EXISTS entries (
+  epoch_secs LONG,
+  nanos INTEGER,
+  level INTEGER,
+  content BLOB,
+  repeat_count INTEGER NOT NULL DEFAULT 1,
+  last_epoch_secs LONG,
+  last_nanos INTEGER,
+  source_file TEXT,
+  source_line INTEGER,
+  function TEXT,
+  stack TEXT,
+  fingerprint TEXT
+);
+`
+
+// ErrEncryptionUnsupported is returned when an encryption key is configured but
+// the linked SQLite library is not SQLCipher.
+var ErrEncryptionUnsupported = errors.New("encryption requires SQLCipher: build with -tags libsqlite3 and link against libsqlcipher")
+
+// SQLiteStore is the default Store, writing the entries to a ring of SQLite
+// database files named logs.N.sqlite, with N from 0 to numLogFiles-1.
+type SQLiteStore struct {
+	dir                string
+	numLogFiles        int
+	encryptionKey      func(name string) ([]byte, error)
+	audit              bool
+	vacuumOnClose      bool
+	compressRotated    bool
+	maxHealthyWALSize  int64
+	walCheckpointEvery time.Duration
+	walSizeLimit       int64
+	attrTable          bool
+	attrKeys           []string
+	indexedAttrs       []string
+	blobTable          bool
+	naming             RotationNaming
+	basename           string
+	instance           string
+	appendOnly         bool
+	indexedColumns     map[string]string
+
+	mu           ctxMutex
+	lockFile     *os.File
+	singleDB     *sql.DB
+	ownDB        bool
+	identity     Identity
+	currentName  string
+	currentLogId int
+	db           *sql.DB
+	chainHash    []byte
+	lastRowId    int64
+
+	// The hash the last entry is chained to, to hash it again when it is repeated
+	lastPrevHash []byte
+
+	stopCheckpoints chan struct{}
+	checkpointsDone chan struct{}
+	checkpointErr   error
+}
+
+// SQLiteOptions configures a SQLiteStore.
+type SQLiteOptions struct {
+	// Basename is the prefix of the names of the database files, like "payments"
+	// for payments.0.sqlite. If empty, the files are named logs.N.sqlite.
+	Basename string
+
+	// Dir is the directory of the database files.
+	// If empty, the current directory is used. With MemoryDir, the entries are
+	// kept in an in-memory database.
+	Dir string
+
+	// NumLogFiles is the number of database files in the rotation.
+	// If zero, 7 files are used.
+	NumLogFiles int
+
+	// EncryptionKey, if not nil, returns the 32-byte key used to encrypt the
+	// database file with the given name. It is called every time a file is opened,
+	// so keys can be rotated together with the files.
+	// Opening a file fails if the key is empty or SQLCipher is not available.
+	EncryptionKey func(name string) ([]byte, error)
+
+	// Audit enables the tamper-evident mode, where each entry stores a hash
+	// chained to the previous entry, across rotated files (see Verify).
+	// The live file of the previous run is sealed on Open instead of recreated.
+	Audit bool
+
+	// VacuumOnClose compacts the live database file when the store is closed.
+	VacuumOnClose bool
+
+	// CompressRotated compacts each file sealed by rotation and compresses it with
+	// zstd to logs.N.sqlite.zst, removing the original. Use OpenLogFile to read it.
+	CompressRotated bool
+
+	// MaxHealthyWALSize is the size of the WAL file above which Healthy reports the
+	// store as unhealthy. If zero, 64 MiB is used.
+	MaxHealthyWALSize int64
+
+	// WALCheckpointEvery, if positive, checkpoints the WAL into the live database
+	// file in the background at this interval, instead of leaving it to the
+	// automatic checkpoints of SQLite. The checkpoints are passive: they do not
+	// wait for the readers, and the inserts wait for them.
+	WALCheckpointEvery time.Duration
+
+	// WALSizeLimit, if positive, truncates the WAL file in the background when it
+	// grows over this size, checked every WALCheckpointEvery or every 10 seconds.
+	WALSizeLimit int64
+
+	// AttrTable stores the attributes of every entry in the attrs table too, with
+	// the keys qualified by their groups, so entries can be selected by attribute
+	// with Query.Attrs.
+	AttrTable bool
+
+	// AttrKeys, if not nil, restricts the attributes stored in the attrs table to
+	// those with these keys, reducing the cost of the writes.
+	AttrKeys []string
+
+	// IndexedAttrs are the keys of the attributes stored in dedicated, indexed
+	// columns of the entries table, like "request_id" or "tenant". The column is
+	// named as the key, with the characters not valid in a column name replaced
+	// by underscores.
+	IndexedAttrs []string
+
+	// BlobTable stores the full values of the message and the attributes truncated
+	// by the size limits of the handler in the blobs table.
+	BlobTable bool
+
+	// Naming is the naming scheme of the files. By default they are a ring of
+	// numbered files. See RotationNaming.
+	Naming RotationNaming
+
+	// Instance, if not empty, is added to the names of the files, like
+	// logs-worker1.0.sqlite, so several processes can log to the same directory.
+	// It can contain letters, digits, '-' and '_'. Only one process can write the
+	// files of an instance: Open fails with ErrLocked if they are in use.
+	Instance string
+
+	// AppendOnly enables the write-once mode, for regulations which forbid
+	// overwriting logs: the files are named by timestamp and never deleted or
+	// reused, each file is made read-only when it is sealed, tables are never
+	// dropped, and the entries are written through connections which can only
+	// insert, while triggers reject updates and deletes from any connection.
+	// It can not be used with MemoryDir, NewSQLiteStoreWithDB, CompressRotated,
+	// VacuumOnClose or coalescing, and NumLogFiles is ignored.
+	AppendOnly bool
+}
+
+// NewSQLiteStore returns a store using a ring of database files as specified by opts.
+func NewSQLiteStore(opts *SQLiteOptions) *SQLiteStore {
+	s := &SQLiteStore{
+		mu:                newCtxMutex(),
+		basename:          logFileBasename,
+		dir:               ".",
+		numLogFiles:       defaultNumLogFiles,
+		maxHealthyWALSize: defaultMaxHealthyWALSize,
+	}
+
+	if opts != nil {
+		if opts.Dir != "" {
+			s.dir = opts.Dir
+		}
+		if opts.NumLogFiles != 0 {
+			s.numLogFiles = opts.NumLogFiles
+		}
+		s.encryptionKey = opts.EncryptionKey
+		s.audit = opts.Audit
+		s.vacuumOnClose = opts.VacuumOnClose
+		s.compressRotated = opts.CompressRotated
+		if opts.MaxHealthyWALSize != 0 {
+			s.maxHealthyWALSize = opts.MaxHealthyWALSize
+		}
+		s.walCheckpointEvery = opts.WALCheckpointEvery
+		s.walSizeLimit = opts.WALSizeLimit
+		s.attrTable = opts.AttrTable
+		s.attrKeys = opts.AttrKeys
+		s.indexedAttrs = opts.IndexedAttrs
+		s.blobTable = opts.BlobTable
+		s.naming = opts.Naming
+		if opts.AppendOnly {
+			s.appendOnly = true
+			s.naming = ByTimestamp
+		}
+		if opts.Basename != "" {
+			s.basename = opts.Basename
+		}
+		if opts.Instance != "" {
+			s.instance = opts.Instance
+			s.basename += "-" + opts.Instance
+		}
+	}
+
+	return s
+}
+
+// fileName returns the path of the database file with the given number in the ring.
+func (s *SQLiteStore) fileName(logId int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s.%d.%s", s.basename, logId, logFileExtension))
+}
+
+// CurrentName returns the path of the live database file.
+func (s *SQLiteStore) CurrentName() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.currentName
+}
+
+func (s *SQLiteStore) Open() (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer func() {
+		if err == nil {
+			s.startCheckpointer()
+		}
+	}()
+
+	if s.instance != "" && !validInstance.MatchString(s.instance) {
+		return fmt.Errorf("invalid instance name %q", s.instance)
+	}
+	if !validInstance.MatchString(s.basename) {
+		return fmt.Errorf("invalid base name %q", s.basename)
+	}
+
+	if s.appendOnly && (s.single() || s.compressRotated || s.vacuumOnClose) {
+		return fmt.Errorf("append-only mode requires database files, and can not be used with compression or vacuum")
+	}
+
+	indexedColumns, err := indexedColumns(s.indexedAttrs)
+	if err != nil {
+		return err
+	}
+	s.indexedColumns = indexedColumns
+
+	if s.single() {
+		return s.openSingle()
+	}
+
+	if err := s.lock(); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			s.unlock()
+		}
+	}()
+
+	// The chain of the live file continues from the previous file
+	var prevName string
+
+	if s.naming == ByTimestamp {
+		// A new file is started, after the newest one
+		files, err := s.timestampFiles()
+		if err != nil {
+			return err
+		}
+		if len(files) > 0 {
+			prevName = files[len(files)-1]
+		}
+		s.currentName, err = s.timestampName(time.Now())
+		if err != nil {
+			return err
+		}
+	} else {
+		// Determine the current database being used from the possible many in the rotation
+		currentName, currentLogId, err := determineCurrentName(s.dir, s.basename, s.numLogFiles)
+		if err != nil {
+			return err
+		}
+		s.currentName = filepath.Join(s.dir, currentName)
+		s.currentLogId = currentLogId
+
+		if err := s.recoverCorrupt(s.currentName); err != nil {
+			return err
+		}
+
+		if _, err := os.Stat(s.currentName); err == nil && s.audit {
+			// The audited entries of the last run are never dropped: their file is
+			// sealed, and the live file is the next one in the ring
+			prevName = s.currentName
+			s.currentLogId = (s.currentLogId + 1) % s.numLogFiles
+			s.currentName = s.fileName(s.currentLogId)
+			os.Remove(s.currentName + compressedExtension)
+		} else {
+			// The live file is recreated
+			prevName = s.ringFile((s.currentLogId + s.numLogFiles - 1) % s.numLogFiles)
+		}
+	}
+
+	if s.audit {
+		s.chainHash, err = s.lastHash(prevName)
+		if err != nil {
+			return err
+		}
+	}
+
+	db, err := s.openLiveDB(s.currentName, openLogSQL)
+	if err != nil {
+		return err
+	}
+
+	s.db = db
+
+	if s.naming == ByTimestamp {
+		return s.pruneTimestampFiles()
+	}
+
+	return nil
+}
+
+// createSchema creates the tables of a fresh live database with the given SQL,
+// and those of the enabled features.
+func (s *SQLiteStore) createSchema(db *sql.DB, schemaSQL string) error {
+	if _, err := db.Exec(schemaSQL); err != nil {
+		return err
+	}
+
+	if err := s.writeMeta(db); err != nil {
+		return err
+	}
+
+	if err := s.createIndexedColumns(db); err != nil {
+		return err
+	}
+
+	if s.attrTable {
+		if _, err := db.Exec(attrTableSQL); err != nil {
+			return fmt.Errorf("creating attrs table: %w", err)
+		}
+	}
+
+	if s.blobTable {
+		if _, err := db.Exec(blobTableSQL); err != nil {
+			return fmt.Errorf("creating blobs table: %w", err)
+		}
+	}
+
+	if s.audit {
+		if err := startChain(db, s.chainHash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) Insert(e Entry) (int64, error) {
+	return s.InsertContext(context.Background(), e)
+}
+
+// InsertContext inserts an entry like Insert, abandoning it if ctx is done before
+// the transaction is committed. SQLite does not check the context while waiting
+// for a locked database, so the wait is limited instead to the deadline of ctx.
+func (s *SQLiteStore) InsertContext(ctx context.Context, e Entry) (int64, error) {
+	if err := s.mu.LockContext(ctx); err != nil {
+		return 0, fmt.Errorf("inserting log record: %w", err)
+	}
+	defer s.mu.Unlock()
+
+	var sourceFile, function sql.NullString
+	var sourceLine sql.NullInt64
+	if e.Source != nil {
+		sourceFile = sql.NullString{String: e.Source.File, Valid: true}
+		sourceLine = sql.NullInt64{Int64: int64(e.Source.Line), Valid: true}
+		function = sql.NullString{String: e.Source.Function, Valid: true}
+	}
+
+	stack, err := encodeStack(e.Stack)
+	if err != nil {
+		return 0, err
+	}
+
+	var fingerprint sql.NullString
+	if e.Fingerprint != "" {
+		fingerprint = sql.NullString{String: e.Fingerprint, Valid: true}
+	}
+
+	columns := []string{"epoch_secs", "nanos", "level", "content", "repeat_count", "source_file", "source_line", "function", "stack", "fingerprint"}
+	args := []any{e.Time.Unix(), e.Time.Nanosecond(), e.Level, e.Content, 1, sourceFile, sourceLine, function, stack, fingerprint}
+
+	for i, value := range s.indexedValues(e.Attrs) {
+		columns = append(columns, s.indexedColumns[s.indexedAttrs[i]])
+		args = append(args, value)
+	}
+
+	var attrs []slog.Attr
+	if s.attrTable {
+		attrs = s.storedAttrs(e.Attrs)
+	}
+
+	var hash []byte
+	if s.audit {
+		hash, err = s.entryHash(columns, args, attrs, e.Blobs)
+		if err != nil {
+			return 0, err
+		}
+		columns = append(columns, "hash")
+		args = append(args, hash)
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("inserting log record: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		wait := min(time.Until(deadline), defaultBusyTimeout)
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("PRAGMA busy_timeout = %d", wait.Milliseconds())); err != nil {
+			return 0, fmt.Errorf("inserting log record: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), fmt.Sprintf("PRAGMA busy_timeout = %d", defaultBusyTimeout.Milliseconds()))
+	}
+
+	// The entry and its attributes are written in a single transaction
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("inserting log record: %w", err)
+	}
+	defer tx.Rollback()
+
+	params := strings.Repeat(", ?", len(columns))[2:]
+	result, err := tx.ExecContext(ctx, "insert into entries ("+strings.Join(columns, ", ")+") values("+params+")", args...)
+	if err != nil {
+		return 0, fmt.Errorf("inserting log record: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("retrieving last insert id: %w", err)
+	}
+
+	if len(attrs) > 0 {
+		if err := insertAttrs(ctx, tx, id, attrs); err != nil {
+			return 0, err
+		}
+	}
+
+	if s.blobTable && len(e.Blobs) > 0 {
+		if err := insertBlobs(ctx, tx, id, e.Blobs); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("inserting log record: %w", err)
+	}
+
+	if s.audit {
+		s.lastPrevHash = s.chainHash
+		s.chainHash = hash
+	}
+	s.lastRowId = id
+
+	return id, nil
+}
+
+// RepeatLast records that the last entry inserted has been repeated, with count
+// being the total number of times it was logged.
+func (s *SQLiteStore) RepeatLast(count int64, last time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.appendOnly {
+		return fmt.Errorf("append-only log entries can not be updated")
+	}
+
+	if s.audit {
+		return s.repeatLastChained(count, last)
+	}
+
+	_, err := s.db.Exec("UPDATE entries SET repeat_count = ?, last_epoch_secs = ?, last_nanos = ? WHERE rowid = ?",
+		count, last.Unix(), last.Nanosecond(), s.lastRowId)
+	if err != nil {
+		return fmt.Errorf("updating repeated log record: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) Rotate() error {
+	if s.single() {
+		return s.rotateSingle()
+	}
+
+	s.mu.Lock()
+
+	sealedName := s.currentName
+	sealedDB := s.db
+
+	var nextLogId int
+	var nextName string
+	var err error
+
+	if s.naming == ByTimestamp {
+		nextName, err = s.timestampName(time.Now())
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+	} else {
+		// Increment the log ID
+		nextLogId = s.currentLogId + 1
+		if nextLogId >= s.numLogFiles {
+			nextLogId = 0
+		}
+
+		// Get the next file name
+		nextName = s.fileName(nextLogId)
+
+		// The compressed copy of the file previously in this position is discarded
+		os.Remove(nextName + compressedExtension)
+	}
+
+	// Open the new log database and create the tables
+	db, err := s.openLiveDB(nextName, resetLogSQL)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+
+	s.currentLogId = nextLogId
+	s.currentName = nextName
+	s.db = db
+	s.lastRowId = 0
+
+	s.mu.Unlock()
+
+	// Close the sealed log database, leaving it ready to be copied.
+	// This is done without the lock, so logging continues in the new file meanwhile.
+	if s.compressRotated {
+		err = compressDB(sealedDB, sealedName)
+	} else {
+		err = closeDB(sealedDB, false)
+	}
+	if s.appendOnly && err == nil {
+		err = seal(sealedName)
+	}
+
+	if s.naming == ByTimestamp {
+		err = errors.Join(err, s.pruneTimestampFiles())
+	}
+
+	return err
+}
+
+func (s *SQLiteStore) Query(ctx context.Context, q Query) ([]Entry, error) {
+	s.mu.Lock()
+	db := s.db
+	s.mu.Unlock()
+
+	return queryEntries(ctx, db, q, s.indexedColumns)
+}
+
+// Close checkpoints the WAL into the live database file and closes it, so the
+// files are clean for copying or archiving immediately after shutdown.
+func (s *SQLiteStore) Close() error {
+	s.stopCheckpointer()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.single() {
+		return s.closeSingle()
+	}
+	err := closeDB(s.db, s.vacuumOnClose)
+	if s.appendOnly && err == nil {
+		err = seal(s.currentName)
+	}
+	return errors.Join(err, s.unlock())
+}
+
+// closeDB truncates the WAL of the database after moving its contents to the
+// database file, optionally compacts the file, and closes the database.
+func closeDB(db *sql.DB, vacuum bool) error {
+	var errs []error
+
+	if _, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		errs = append(errs, fmt.Errorf("checkpointing WAL: %w", err))
+	}
+
+	if vacuum {
+		if _, err := db.Exec("VACUUM"); err != nil {
+			errs = append(errs, fmt.Errorf("vacuuming database: %w", err))
+		}
+	}
+
+	if err := db.Close(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// openDB opens the database file with the given name, keying the connections
+// if the store is encrypted.
+func (s *SQLiteStore) openDB(name string) (*sql.DB, error) {
+	return s.openKeyedDB(name, name)
+}
+
+// openKeyedDB opens the database file with the given name, using the encryption
+// key of the file keyName.
+func (s *SQLiteStore) openKeyedDB(name string, keyName string) (*sql.DB, error) {
+	return s.openAuthorizedDB(name, keyName, nil)
+}
+
+// openAuthorizedDB opens the database file with the given name, using the
+// encryption key of the file keyName, with the authorizer, if not nil, registered
+// in every connection.
+func (s *SQLiteStore) openAuthorizedDB(name string, keyName string, authorizer func(op int, arg1, arg2, arg3 string) int) (*sql.DB, error) {
+	if s.encryptionKey == nil && authorizer == nil {
+		return sql.Open("sqlite3", name)
+	}
+
+	var keyPragma string
+	if s.encryptionKey != nil {
+		key, err := s.encryptionKey(keyName)
+		if err != nil {
+			return nil, fmt.Errorf("getting encryption key for %s: %w", name, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("encryption key for %s must be 32 bytes, got %d", name, len(key))
+		}
+		keyPragma = fmt.Sprintf(`PRAGMA key = "x'%s'"`, hex.EncodeToString(key))
+	}
+
+	drv := &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			// The key must be set before any other statement in every new connection
+			if keyPragma != "" {
+				execer, ok := any(conn).(driver.ExecerContext)
+				if !ok {
+					return ErrEncryptionUnsupported
+				}
+				if _, err := execer.ExecContext(context.Background(), keyPragma, nil); err != nil {
+					return err
+				}
+			}
+			if authorizer != nil {
+				conn.RegisterAuthorizer(authorizer)
+			}
+			return nil
+		},
+	}
+	db := sql.OpenDB(&sqliteConnector{name: name, drv: drv})
+
+	if s.encryptionKey == nil {
+		return db, nil
+	}
+
+	// Fail closed if the library ignores the key, instead of writing a plaintext file
+	var cipherVersion string
+	err := db.QueryRow("PRAGMA cipher_version").Scan(&cipherVersion)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && cipherVersion == "") {
+		db.Close()
+		return nil, ErrEncryptionUnsupported
+	}
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening encrypted database %s: %w", name, err)
+	}
+
+	return db, nil
+}
+
+// sqliteConnector opens connections to a database file with a custom driver.
+type sqliteConnector struct {
+	name string
+	drv  *sqlite3.SQLiteDriver
+}
+
+func (c *sqliteConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.drv.Open(c.name)
+}
+
+func (c *sqliteConnector) Driver() driver.Driver {
+	return c.drv
+}
+
+// queryEntries runs q against a database with the schema of SQLiteStore, where
+// indexedColumns are the columns of the indexed attributes.
+func queryEntries(ctx context.Context, db *sql.DB, q Query, indexedColumns map[string]string) ([]Entry, error) {
+	where, args := queryWhere(q, indexedColumns)
+
+	stmt := "SELECT rowid, epoch_secs, nanos, level, content, repeat_count, last_epoch_secs, last_nanos, source_file, source_line, function, stack FROM entries" + where
+	stmt += " ORDER BY epoch_secs, nanos, rowid"
+	if q.Limit > 0 {
+		stmt += " LIMIT " + strconv.Itoa(q.Limit)
+	}
+
+	rows, err := db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying log entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		var secs, nanos int64
+		var level int
+		var lastSecs, lastNanos, sourceLine sql.NullInt64
+		var sourceFile, function, stack sql.NullString
+		if err := rows.Scan(&e.ID, &secs, &nanos, &level, &e.Content, &e.RepeatCount, &lastSecs, &lastNanos, &sourceFile, &sourceLine, &function, &stack); err != nil {
+			return nil, fmt.Errorf("reading log entry: %w", err)
+		}
+		if e.Stack, err = DecodeStack(stack.String); err != nil {
+			return nil, err
+		}
+		e.Time = time.Unix(secs, nanos)
+		e.Level = slog.Level(level)
+		if lastSecs.Valid {
+			e.LastTime = time.Unix(lastSecs.Int64, lastNanos.Int64)
+		}
+		if sourceFile.Valid {
+			e.Source = &slog.Source{Function: function.String, File: sourceFile.String, Line: int(sourceLine.Int64)}
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// queryWhere returns the WHERE clause selecting the entries of q, if any, and its
+// arguments. The Limit of q is not included.
+func queryWhere(q Query, indexedColumns map[string]string) (string, []any) {
+	var where []string
+	var args []any
+
+	if !q.Since.IsZero() {
+		where = append(where, "(epoch_secs > ? OR (epoch_secs = ? AND nanos >= ?))")
+		args = append(args, q.Since.Unix(), q.Since.Unix(), q.Since.Nanosecond())
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "(epoch_secs < ? OR (epoch_secs = ? AND nanos < ?))")
+		args = append(args, q.Until.Unix(), q.Until.Unix(), q.Until.Nanosecond())
+	}
+	if q.MinLevel != nil {
+		where = append(where, "level >= ?")
+		args = append(args, int(q.MinLevel.Level()))
+	}
+	if q.Contains != "" {
+		where = append(where, "instr(content, ?) > 0")
+		args = append(args, q.Contains)
+	}
+	if q.SourceFile != "" {
+		where = append(where, "source_file = ?")
+		args = append(args, q.SourceFile)
+	}
+	for key, value := range q.Attrs {
+		if v, ok := value.(slog.Value); ok {
+			_, value = attrColumnValue(v.Resolve())
+		}
+		if column, ok := indexedColumns[key]; ok {
+			where = append(where, column+" = ?")
+			args = append(args, value)
+			continue
+		}
+		where = append(where, "rowid IN (SELECT entry_id FROM attrs WHERE key = ? AND value = ?)")
+		args = append(args, key, value)
+	}
+
+	if len(where) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(where, " AND "), args
+}
+
+// DetermineCurrentName returns the name of the live log file in the current directory.
+func DetermineCurrentName() (string, error) {
+	name, _, err := determineCurrentName(".", logFileBasename, defaultNumLogFiles)
+	return name, err
+}
+
+func determineCurrentName(dir string, basename string, numLogFiles int) (string, int, error) {
+
+	// Read all entries in the directory
+	dirEntry, err := os.ReadDir(dir)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var candidateFileName string
+	candidateLogNumber := 0
+	minimumModificationTime := int64(0)
+
+	for _, entry := range dirEntry {
+		// Skip entries which are directories and handle only files
+		if entry.IsDir() {
+			continue
+		}
+
+		// Skip files with a name not according to the pattern name.aNumber.extension
+		parts := strings.Split(entry.Name(), ".")
+		if len(parts) != 3 {
+			continue
+		}
+
+		// Skip files without the exact name and extension
+		if parts[0] != basename || parts[2] != logFileExtension {
+			continue
+		}
+
+		// We found a log file, check its modification time agains the current minimum
+		info, err := entry.Info()
+		if err != nil {
+			return "", 0, err
+		}
+
+		if info.ModTime().Unix() < minimumModificationTime {
+			continue
+		}
+
+		// We will account for the very strange case where two log files have the same modification time
+		// We will choose the one with greater log number or when the current entry number is 0 and
+		// the candidate is numLogFiles-1
+
+		// The restored files are not in the ring
+		entryLogNumber, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+
+		if (entryLogNumber > candidateLogNumber) || (entryLogNumber == 0 && candidateLogNumber == numLogFiles-1) {
+			minimumModificationTime = info.ModTime().Unix()
+			candidateFileName = entry.Name()
+			candidateLogNumber = entryLogNumber
+		}
+
+	}
+
+	// If we are starting the first time, we would not find any files complying with the naming
+	if candidateFileName == "" {
+		return fmt.Sprintf("%s.%d.%s", basename, 0, logFileExtension), 0, nil
+	} else {
+		return candidateFileName, candidateLogNumber, nil
+	}
+}
@@ -1,91 +1,306 @@
 package sqlogger
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
-	"runtime"
+	"slices"
 	"strconv"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/fatih/color"
-	_ "github.com/mattn/go-sqlite3"
 )
 
 const defaultMaxSizeLiveLog = 50000
 const defaultNumLogFiles = 7
-
-const logFileBasename = "logs"
-const logFileExtension = "sqlite"
-
-const openLogSQL = `
-PRAGMA journal_mode = WAL;
-PRAGMA synchronous = NORMAL;
-PRAGMA busy_timeout = 5000;
-
-DROP TABLE IF EXISTS entries;
-
-CREATE TABLE IF NOT EXISTS entries (
-  epoch_secs LONG,
-  nanos INTEGER, 
-  level INTEGER,  
-  content BLOB
-);
-`
-const resetLogSQL = `
-PRAGMA journal_mode = WAL;
-PRAGMA synchronous = NORMAL;
-PRAGMA busy_timeout = 5000;
-
-DROP TABLE IF EXISTS entries;
-
-CREATE TABLE IF NOT EXISTS entries (
-  epoch_secs LONG,
-  nanos INTEGER, 
-  level INTEGER,  
-  content BLOB
-);
-`
+const defaultMaxBinaryAttrBytes = 64 << 10
 
 // groupOrAttrs holds either a group name or a list of slog.Attrs.
 type groupOrAttrs struct {
-	group string      // group name if non-empty
-	attrs []slog.Attr // attrs if non-empty
+	group  string      // group name if non-empty
+	attrs  []slog.Attr // attrs if non-empty
+	prefix string      // the prefix of the keys of attrs, from the groups before them
 }
 
 type SQLogger struct {
 	opts         Options
+	level        *slog.LevelVar
 	goas         []groupOrAttrs
-	currentName  string
-	currentLogId int
-	db           *sql.DB
-	lastInsertId int64
+	prefix       string
+	store        Store
+	rotation     *rotation
+	named        *namedSet
+	main         *SQLogger
+	isNamed      bool
+	coalescer    *coalescer
+	retry        *retryQueue
+	metrics      *metrics
+	identity     Identity
+	storeAttrs   bool
+	storeBlobs   bool
+	palette      *palette
+	console      io.Writer
+	asyncConsole *asyncConsole
+	levelNames   map[slog.Level]string
+	levelStorage map[slog.Level]LevelStorage
+	rule         slog.Leveler
 	stdHandler   slog.Handler
 	cwd          string
 }
 
+// rotation counts the entries of the live storage unit, shared by the handlers
+// derived with WithAttrs and WithGroup. The mutex is held while rotating, so the
+// sealed entries are not discounted twice.
+type rotation struct {
+	mu      sync.Mutex
+	entries atomic.Int64
+}
+
 type Options struct {
 	// Level reports the minimum level to log.
 	// Levels with lower levels are discarded.
 	// If nil, the Handler uses [slog.LevelInfo].
+	// A *slog.LevelVar is used directly, so changes to it are seen by the handler.
+	// Any other Leveler is resolved once; use SetLevel to change it afterwards.
 	Level slog.Leveler
 
-	// The maximum number of log entries per database file
-	maxSizeLiveLog int
-
-	// The number of database files for log rotation
-	numLogFiles int
-
-	// Set to true to disable color output to console
+	// MaxEntries is the number of entries of the live storage unit which triggers a
+	// rotation, counted by the handler since the unit was started, including the
+	// entries already there when the handler is created, if the store implements
+	// EntryCounter. The entries logged concurrently while rotating can exceed it
+	// slightly. If zero, 50000 entries are stored per unit.
+	MaxEntries int
+
+	// NumLogFiles is the number of database files of the default store, rotated in a
+	// ring or, with ByTimestamp, kept before deleting the oldest.
+	// If zero, 7 files are used.
+	NumLogFiles int
+
+	// The name of the rotation set of a handler returned by Named
+	name string
+
+	// CopyNamedToMain also stores the records of the handlers returned by Named in
+	// the main files, besides their own files. The records are sampled, enriched and
+	// rendered once, and the same entry is written to both.
+	CopyNamedToMain bool
+
+	// Enrichers are called in order on every record logged, before it is redacted,
+	// printed, stored or sent to the sinks, to add attributes derived from it, like
+	// the location of a remote address with the geoip package. They run in the
+	// goroutine of the caller, so they must be fast and safe for concurrent use.
+	Enrichers []func(r *slog.Record)
+
+	// Redaction, if not nil, masks sensitive values like passwords, tokens or email
+	// addresses before the records are printed, stored or sent to the sinks.
+	Redaction *Redaction
+
+	// MaxMessageBytes and MaxAttrBytes, if positive, limit the size of the message
+	// and of each attribute value, truncating them without splitting UTF-8
+	// characters. Values which are not strings are limited by their text.
+	// Truncated records get the attribute truncated=true.
+	MaxMessageBytes int
+	MaxAttrBytes    int
+
+	// MaxBinaryAttrBytes limits the size of the []byte attribute values, which are
+	// printed in hex and stored as blobs in the attrs table, so protocol dumps and
+	// hashes are read back intact. Longer values are truncated like the others.
+	// If zero, 64 KiB is used.
+	MaxBinaryAttrBytes int
+
+	// BlobTable stores the full values of the message and the attributes truncated
+	// in the blobs table of the default store, keyed by the rowid of the entry.
+	BlobTable bool
+
+	// ExpandErrors expands the first error attribute of each record: the messages
+	// of the errors it wraps are added as an attribute with the key of the error
+	// followed by ".chain", and the stack it carries, from WithStack or from
+	// github.com/pkg/errors, is printed below the line and stored in the stack column.
+	ExpandErrors bool
+
+	// Service is the name of the service recorded with the entries, together with
+	// the hostname and the PID. If empty, the name of the executable is used.
+	Service string
+
+	// Set to true to disable color output to console, like Color: ColorNever
 	NoColor bool
+
+	// Color controls the colors of the console output. By default they are used
+	// only when stdout is a terminal. See ColorMode.
+	Color ColorMode
+
+	// SyncOnLevel, if not nil, makes the entries with this level or higher durable
+	// as soon as they are inserted: the entries waiting for a retry are written
+	// first, and the WAL is checkpointed, if the store implements Syncer.
+	// Use it so WARN or ERROR entries survive a crash.
+	SyncOnLevel slog.Leveler
+
+	// LevelRules set the minimum level of the loggers derived with WithGroup or
+	// WithAttrs, overriding Level. A key is either a group path, like "http" or
+	// "http.client", matching the loggers in that group and its subgroups, or a
+	// string attribute, like "component=db". The longest matching key wins.
+	LevelRules map[string]slog.Leveler
+
+	// LevelNames are the names of additional levels, like LevelTrace: "TRACE",
+	// shown in the console and stored instead of names like "DEBUG-4".
+	// Their colors are set in the Levels of the Theme.
+	LevelNames map[slog.Leveler]string
+
+	// LevelStorage configures how the entries of each level are stored, like
+	// syncing every entry of an audit level. See LevelStorage.
+	LevelStorage map[slog.Leveler]LevelStorage
+
+	// Theme holds the colors of the console output. If nil, DefaultTheme is used.
+	Theme *Theme
+
+	// Layout configures the format of the console output.
+	Layout Layout
+
+	// Console is the destination of the console output. If nil, os.Stdout is used.
+	// Set it to io.Discard to log only to the store.
+	Console io.Writer
+
+	// AsyncConsole renders and prints the console lines in a separate goroutine, so
+	// heavy logging does not wait for a slow terminal or pipe. The lines wait in a
+	// queue of ConsoleQueueSize lines, 1024 by default, and the oldest are dropped
+	// when it is full, counted in Stats.ConsoleDropped. The store still receives
+	// every entry before Handle returns.
+	AsyncConsole     bool
+	ConsoleQueueSize int
+
+	// NoSource omits the location of the log call, which is otherwise printed to the
+	// console and stored in the source_file, source_line and function columns.
+	// Resolving the location has a cost, which is saved when it is not needed.
+	NoSource bool
+
+	// Store is the storage backend for the log entries.
+	// If nil, a SQLiteStore in Dir is used.
+	Store Store
+
+	// Dir is the directory of the files of the default store. If empty, the current
+	// directory is used. With MemoryDir, the entries are kept in memory.
+	Dir string
+
+	// EncryptionKey is the 32-byte key used to encrypt the database files with
+	// SQLCipher. If set and SQLCipher is not available, NewSQLogger fails.
+	EncryptionKey []byte
+
+	// EncryptionKeyFunc returns the key for each database file, and is called
+	// every time a file is opened, allowing key rotation on file rotation.
+	// It takes precedence over EncryptionKey.
+	EncryptionKeyFunc func(file string) ([]byte, error)
+
+	// Audit enables the tamper-evident mode of the default store, where each entry
+	// is chained to the previous one with a SHA-256 hash. See SQLogger.Verify.
+	Audit bool
+
+	// VacuumOnClose compacts the live database file of the default store in Close.
+	VacuumOnClose bool
+
+	// WALCheckpointEvery and WALSizeLimit control the WAL of the default store:
+	// the WAL is checkpointed in the background every WALCheckpointEvery, and
+	// truncated when it grows over WALSizeLimit bytes. See SQLiteOptions.
+	WALCheckpointEvery time.Duration
+	WALSizeLimit       int64
+
+	// CompressRotated compacts and compresses with zstd each database file sealed by
+	// rotation of the default store, to logs.N.sqlite.zst. See OpenLogFile.
+	CompressRotated bool
+
+	// AttrTable stores the attributes of every entry in the attrs table of the
+	// default store too, so entries can be selected by attribute.
+	AttrTable bool
+
+	// AttrKeys, if not nil, restricts the attributes stored in the attrs table to
+	// those with these keys, like "user_id" or "req.method".
+	AttrKeys []string
+
+	// IndexedAttrs are the keys of the attributes stored in dedicated, indexed
+	// columns of the entries table of the default store, for the identifiers
+	// queried most, like "request_id" or "tenant".
+	IndexedAttrs []string
+
+	// RotationNaming is the naming scheme of the files of the default store, either
+	// a ring of numbered files (ByNumber, the default) or files named with the time
+	// they were created (ByTimestamp).
+	RotationNaming RotationNaming
+
+	// AppendOnly enables the write-once mode of the default store, where log files
+	// are never overwritten, deleted or updated, and are made read-only when sealed.
+	// See SQLiteOptions.AppendOnly.
+	AppendOnly bool
+
+	// Instance, if not empty, is added to the names of the files of the default
+	// store, like logs-worker1.0.sqlite, so several processes can log to the same
+	// directory. Without it, a second process fails with ErrLocked.
+	Instance string
+
+	// OnRotate, if not nil, is called after the live database file has been sealed
+	// and the new one has been opened. It runs synchronously in the goroutine which
+	// logged the record triggering the rotation, so slow work like uploading the
+	// closed file should be done in a separate goroutine.
+	// With CompressRotated, closedFile is the compressed file.
+	OnRotate func(closedFile string, newFile string)
+
+	// Sinks receive a copy of every entry, to forward it to other logging systems
+	// like syslog (NewSyslogSink) or journald (NewJournaldSink). They are closed
+	// by Close.
+	Sinks []Sink
+
+	// Sampler, if not nil, limits the number of records logged with the same level
+	// and message per tick. See NewSampler.
+	Sampler *Sampler
+
+	// CoalesceRepeats collapses consecutive records with the same level, message and
+	// attributes into a single stored entry with a repeat count and the time of the
+	// last repetition. The console prints "last message repeated N times" when the
+	// run ends. The store must implement Repeater.
+	CoalesceRepeats bool
+
+	// Fallback receives the plain log line of the records which could not be
+	// inserted in the store, like when the disk is full or the database is locked.
+	// If nil, os.Stderr is used.
+	Fallback io.Writer
+
+	// RetryQueueSize is the maximum number of failed entries kept in memory to
+	// retry them before the next insert. When full, the oldest are dropped.
+	// If zero, 1000 entries are kept. Set it to a negative number to disable retries.
+	RetryQueueSize int
+
+	// WriteTimeout limits the time an entry can wait for the store, like when the
+	// database is locked by another process or by a slow insert of another
+	// goroutine, so the logging goroutines are never blocked indefinitely. The
+	// insert is also abandoned when the context passed to Handle is done. An
+	// abandoned entry is written to Fallback and deferred like any failed insert,
+	// and counted in WriteFailures.TimedOut.
+	// The store must implement ContextInserter, like the default store.
+	// If zero, the inserts wait for the store, and the context of the record is
+	// ignored.
+	WriteTimeout time.Duration
 }
 
 func NewSQLogger(opts *Options) (*SQLogger, error) {
+	return newSQLogger(opts, nil)
+}
+
+// NewSQLoggerWithDB returns a handler writing the entries to the given SQLite
+// database instead of a ring of files, for tests or to use custom connection
+// settings. See NewSQLiteStoreWithDB. The database is not closed by Close.
+func NewSQLoggerWithDB(db *sql.DB, opts *Options) (*SQLogger, error) {
+	if opts != nil && opts.Store != nil {
+		return nil, fmt.Errorf("a custom Store can not be used with a database")
+	}
+	return newSQLogger(opts, db)
+}
+
+// newSQLogger returns a handler, with the default store on db if it is not nil.
+func newSQLogger(opts *Options, db *sql.DB) (*SQLogger, error) {
 
 	h := &SQLogger{}
 
@@ -97,152 +312,249 @@ func NewSQLogger(opts *Options) (*SQLogger, error) {
 	if h.opts.Level == nil {
 		h.opts.Level = slog.LevelInfo
 	}
-	if h.opts.maxSizeLiveLog == 0 {
-		h.opts.maxSizeLiveLog = defaultMaxSizeLiveLog
+	if h.opts.MaxEntries < 0 {
+		return nil, fmt.Errorf("negative maximum number of entries")
 	}
-	if h.opts.numLogFiles == 0 {
-		h.opts.numLogFiles = defaultNumLogFiles
+	if h.opts.MaxEntries == 0 {
+		h.opts.MaxEntries = defaultMaxSizeLiveLog
 	}
-
-	// Enable or disable colored output to console
-	color.NoColor = h.opts.NoColor
-
-	cwd, err := os.Getwd()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get the working directory: %w", err)
+	if h.opts.ConsoleQueueSize < 0 {
+		return nil, fmt.Errorf("negative console queue size")
 	}
-	h.cwd = cwd
-
-	h.stdHandler = slog.Default().Handler()
-
-	// Determine the current database being used from the possible many in the rotation
-	currentName, err := DetermineCurrentName()
-	if err != nil {
-		return nil, err
+	if h.opts.ConsoleQueueSize == 0 {
+		h.opts.ConsoleQueueSize = defaultConsoleQueueSize
 	}
-	h.currentName = currentName
-
-	db, err := sql.Open("sqlite3", h.currentName)
-	if err != nil {
-		return nil, err
+	if h.opts.MaxBinaryAttrBytes < 0 {
+		return nil, fmt.Errorf("negative maximum size of binary attributes")
 	}
-
-	_, err = db.Exec(openLogSQL)
-	if err != nil {
-		return nil, err
+	if h.opts.MaxBinaryAttrBytes == 0 {
+		h.opts.MaxBinaryAttrBytes = defaultMaxBinaryAttrBytes
+	}
+	if h.opts.NumLogFiles < 0 {
+		return nil, fmt.Errorf("negative number of log files")
 	}
+	if h.opts.Fallback == nil {
+		h.opts.Fallback = os.Stderr
+	}
+	if h.opts.RetryQueueSize == 0 {
+		h.opts.RetryQueueSize = defaultRetryQueueSize
+	}
+	h.retry = &retryQueue{mu: newCtxMutex(), size: h.opts.RetryQueueSize}
+	h.metrics = newMetrics()
+	h.rotation = &rotation{}
 
-	h.db = db
+	// Cache the level in a LevelVar, so Enabled is a single atomic load
+	if lv, ok := h.opts.Level.(*slog.LevelVar); ok {
+		h.level = lv
+	} else {
+		h.level = new(slog.LevelVar)
+		h.level.Set(h.opts.Level.Level())
+	}
 
-	return h, nil
+	// Enable or disable colored output to console
+	if h.opts.NoColor {
+		h.opts.Color = ColorNever
+	}
+	console := h.opts.Console
+	if console == nil {
+		console = os.Stdout
+	}
+	colored := h.opts.Color == ColorAlways
+	if f, ok := console.(*os.File); ok {
+		colored = useColor(h.opts.Color, f)
+	}
+	h.palette = newPalette(h.opts.Theme, colored)
 
-}
+	for key := range h.opts.LevelRules {
+		if !validLevelRuleKey(key) {
+			return nil, fmt.Errorf("invalid level rule %q", key)
+		}
+	}
 
-func DetermineCurrentName() (string, error) {
+	h.levelNames = resolveLevels(h.opts.LevelNames)
+	h.levelStorage = resolveLevels(h.opts.LevelStorage)
+	h.console = consoleWriter(console, colored)
 
-	// Read all entries in the current directory
-	dirEntry, err := os.ReadDir(".")
+	cwd, err := os.Getwd()
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("failed to get the working directory: %w", err)
 	}
+	h.cwd = cwd
 
-	var candidateFileName string
-	candidateLogNumber := 0
-	minimumModificationTime := int64(0)
+	h.stdHandler = slog.Default().Handler()
 
-	for _, entry := range dirEntry {
-		// Skip entries which are directories and handle only files
-		if entry.IsDir() {
-			continue
+	if h.opts.Store == nil {
+		h.named = &namedSet{root: h, opts: h.opts}
+		sqliteOpts := &SQLiteOptions{
+			Basename:           h.opts.name,
+			Dir:                h.opts.Dir,
+			NumLogFiles:        h.opts.NumLogFiles,
+			EncryptionKey:      h.opts.EncryptionKeyFunc,
+			Audit:              h.opts.Audit,
+			VacuumOnClose:      h.opts.VacuumOnClose,
+			WALCheckpointEvery: h.opts.WALCheckpointEvery,
+			WALSizeLimit:       h.opts.WALSizeLimit,
+			CompressRotated:    h.opts.CompressRotated,
+			AttrTable:          h.opts.AttrTable,
+			AttrKeys:           h.opts.AttrKeys,
+			IndexedAttrs:       h.opts.IndexedAttrs,
+			BlobTable:          h.opts.BlobTable,
+			Naming:             h.opts.RotationNaming,
+			Instance:           h.opts.Instance,
+			AppendOnly:         h.opts.AppendOnly,
 		}
-
-		// Skip files with a name not according to the pattern name.aNumber.extension
-		parts := strings.Split(entry.Name(), ".")
-		if len(parts) != 3 {
-			continue
+		if sqliteOpts.EncryptionKey == nil && h.opts.EncryptionKey != nil {
+			key := h.opts.EncryptionKey
+			sqliteOpts.EncryptionKey = func(string) ([]byte, error) { return key, nil }
+		}
+		if db != nil {
+			h.opts.Store = NewSQLiteStoreWithDB(db, sqliteOpts)
+		} else {
+			h.opts.Store = NewSQLiteStore(sqliteOpts)
 		}
+	} else if h.opts.Dir != "" || h.opts.NumLogFiles != 0 || h.opts.EncryptionKey != nil || h.opts.EncryptionKeyFunc != nil || h.opts.Audit || h.opts.VacuumOnClose || h.opts.WALCheckpointEvery != 0 || h.opts.WALSizeLimit != 0 || h.opts.CompressRotated || h.opts.AttrTable || h.opts.AttrKeys != nil || h.opts.IndexedAttrs != nil || h.opts.BlobTable || h.opts.RotationNaming != ByNumber || h.opts.Instance != "" || h.opts.AppendOnly {
+		return nil, fmt.Errorf("the options of the default store can not be used with a custom Store")
+	}
+	h.store = h.opts.Store
 
-		// Skip files without the exact name and extension
-		if parts[0] != logFileBasename || parts[2] != logFileExtension {
-			continue
+	if s, ok := h.store.(*SQLiteStore); ok {
+		h.storeAttrs = s.attrTable || len(s.indexedAttrs) > 0
+		h.storeBlobs = s.blobTable
+		if s.appendOnly && h.opts.CoalesceRepeats {
+			return nil, fmt.Errorf("repeats can not be coalesced in append-only mode")
 		}
+	}
 
-		// We found a log file, check its modification time agains the current minimum
-		info, err := entry.Info()
-		if err != nil {
-			return "", err
+	h.identity = newIdentity(h.opts.Service)
+	if is, ok := h.store.(IdentitySetter); ok {
+		is.SetIdentity(h.identity)
+	}
+
+	if h.opts.CoalesceRepeats {
+		if _, ok := h.store.(Repeater); !ok {
+			return nil, fmt.Errorf("the store does not support coalescing repeats")
 		}
+		h.coalescer = &coalescer{}
+	}
 
-		if info.ModTime().Unix() < minimumModificationTime {
-			continue
+	if h.opts.WriteTimeout > 0 {
+		if _, ok := h.store.(ContextInserter); !ok {
+			return nil, fmt.Errorf("the store does not support write timeouts")
 		}
+	}
 
-		// We will account for the very strange case where two log files have the same modification time
-		// We will choose the one with greater log number or when the current entry number is 0 and
-		// the candidate is numLogFiles-1
+	if err := h.store.Open(); err != nil {
+		return nil, err
+	}
 
-		entryLogNumber, err := strconv.Atoi(parts[1])
+	// The live storage unit may already have entries, like with a custom store
+	if c, ok := h.store.(EntryCounter); ok {
+		n, err := c.CountLive()
 		if err != nil {
-			return "", err
+			h.store.Close()
+			return nil, err
 		}
-
-		if (entryLogNumber > candidateLogNumber) || (entryLogNumber == 0 && candidateLogNumber == defaultNumLogFiles-1) {
-			minimumModificationTime = info.ModTime().Unix()
-			candidateFileName = entry.Name()
-			candidateLogNumber = entryLogNumber
-		}
-
+		h.rotation.entries.Store(n)
+		h.metrics.observeRows(n)
 	}
 
-	// If we are starting the first time, we would not find any files complying with the naming
-	if candidateFileName == "" {
-		return fmt.Sprintf("%s.%d.%s", logFileBasename, 0, logFileExtension), nil
-	} else {
-		return candidateFileName, nil
+	if h.opts.AsyncConsole {
+		h.asyncConsole = newAsyncConsole(h.console, h.opts.ConsoleQueueSize)
+		h.console = h.asyncConsole
 	}
+
+	return h, nil
+
 }
 
+// Rotate seals the current log database and starts a new one. It waits for a
+// rotation in progress to finish.
 func (h *SQLogger) Rotate() error {
-	// Close the current log database
-	h.db.Close()
+	h.rotation.mu.Lock()
+	defer h.rotation.mu.Unlock()
+	return h.rotate()
+}
+
+// rotate rotates the store, with the rotation mutex held.
+func (h *SQLogger) rotate() error {
+	closedFile := h.store.CurrentName()
 
-	// Increment the log ID
-	h.currentLogId++
-	if h.currentLogId >= defaultNumLogFiles {
-		h.currentLogId = 0
+	// The entries counted until now stay in the sealed unit. Those inserted while
+	// rotating are counted in the new one.
+	sealed := h.rotation.entries.Load()
+	if err := h.store.Rotate(); err != nil {
+		return err
 	}
 
-	// Get the next file name
-	h.currentName = fmt.Sprintf("%s.%d.%s", logFileBasename, h.currentLogId, logFileExtension)
-	slog.Info("rotating log file", "name", h.currentLogId)
+	h.rotation.entries.Add(-sealed)
+	h.metrics.observeRotation()
 
-	// Open the new log database
-	db, err := sql.Open("sqlite3", h.currentName)
-	if err != nil {
-		return err
+	newFile := h.store.CurrentName()
+	slog.Info("rotating log file", "name", newFile)
+
+	if s, ok := h.store.(*SQLiteStore); ok {
+		closedFile = s.sealedName(closedFile)
 	}
 
-	// Create the table
-	_, err = db.Exec(resetLogSQL)
-	if err != nil {
-		return err
+	if h.opts.OnRotate != nil {
+		h.opts.OnRotate(closedFile, newFile)
 	}
 
-	h.db = db
+	return nil
+}
 
+// Sync writes the entries waiting for a retry and makes all the entries stored so
+// far durable, if the store implements Syncer.
+func (h *SQLogger) Sync() error {
+	if err := h.flushRetries(); err != nil {
+		return err
+	}
+	if syncer, ok := h.store.(Syncer); ok {
+		return syncer.Sync()
+	}
 	return nil
 }
 
+// Verify checks the integrity of all the stored entries, returning a *TamperError
+// for the first entry which has been modified or deleted.
+// The store must support verification, like the default store in audit mode.
+func (h *SQLogger) Verify(ctx context.Context) error {
+	v, ok := h.store.(Verifier)
+	if !ok {
+		return fmt.Errorf("the store does not support verification")
+	}
+	return v.Verify(ctx)
+}
+
 func (h *SQLogger) Name() string {
 	return "SQLogger"
 }
 
+// Store returns the store of the handler, to query the entries.
+func (h *SQLogger) Store() Store {
+	return h.store
+}
+
 func (h *SQLogger) Enabled(ctx context.Context, level slog.Level) bool {
-	return level >= h.opts.Level.Level()
+	if h.rule != nil {
+		return level >= h.rule.Level()
+	}
+	return level >= h.level.Level()
+}
+
+// SetLevel changes the minimum level to log, for this handler and all the
+// handlers derived from it with WithAttrs and WithGroup, except those matched by
+// Options.LevelRules.
+func (h *SQLogger) SetLevel(level slog.Level) {
+	h.level.Set(level)
 }
 
 func (h *SQLogger) Handle(c context.Context, r slog.Record) error {
+	return h.handle(c, r, nil)
+}
 
+// handle logs a record, with the stack trace stored with the entry, if any.
+func (h *SQLogger) handle(c context.Context, r slog.Record, stack []slog.Source) error {
 	// Get a byte buffer from the pool and defer returning it to the pool
 	bufp := allocBuf()
 	bufColor := *bufp
@@ -264,161 +576,264 @@ func (h *SQLogger) Handle(c context.Context, r slog.Record) error {
 		r.Time = time.Now()
 	}
 
-	const glevel = 130
-	greyColor := color.RGB(glevel, glevel, glevel)
-
-	// The string representation of the log time
-	logTime := r.Time.Format(time.TimeOnly)
-	logTimeColored := greyColor.Sprint(logTime)
+	// Drop repetitive records, recording how many were dropped in the next one logged
+	if h.opts.Sampler != nil {
+		keep, dropped := h.opts.Sampler.sample(&r)
+		if !keep {
+			return nil
+		}
+		if dropped > 0 {
+			r = r.Clone()
+			r.AddAttrs(slog.Int64(SampledDroppedKey, dropped))
+		}
+	}
 
-	// Color the level and set minimum length of 5 chars
-	level := r.Level.String()
+	if len(h.opts.Enrichers) > 0 {
+		// Copies of the record share its attributes
+		enriched := r.Clone()
+		for _, enrich := range h.opts.Enrichers {
+			enrich(&enriched)
+		}
+		r = enriched
+	}
 
-	coloredLevel := level
-	switch r.Level {
-	case slog.LevelDebug:
-		coloredLevel = color.MagentaString(level)
-	case slog.LevelInfo:
-		coloredLevel = color.GreenString(level)
-	case slog.LevelWarn:
-		coloredLevel = color.YellowString(level)
-	case slog.LevelError:
-		coloredLevel = color.RedString(level)
+	// The error chains are expanded first, to be redacted and truncated like the
+	// other attributes
+	if h.opts.ExpandErrors {
+		var errStack []slog.Source
+		r, errStack = h.expandErrors(r)
+		if stack == nil {
+			stack = errStack
+		}
 	}
 
-	var undecoratedLocation string
-	var decoratedLocation string
+	if h.opts.Redaction != nil {
+		r = h.opts.Redaction.redact(r)
+	}
 
-	// The location of the log call
-	if r.PC != 0 {
-		fs := runtime.CallersFrames([]uintptr{r.PC})
-		f, _ := fs.Next()
+	var payloads []slog.Attr
+	if h.opts.MaxMessageBytes > 0 || h.opts.MaxAttrBytes > 0 || h.hasLargeBinary(r) {
+		r, payloads = h.truncate(r)
+	}
 
-		dir, file := filepath.Split(f.File)
+	// The records of a named handler can also go to the main files. The entry is
+	// built once, and the main handler prints it and sends it to the sinks.
+	out := h
+	if h.main != nil {
+		out = h.main
+	}
 
-		// Trim the root directory prefix to get the relative directory of the source file
-		var fullFileName string
-		relativeDir, err := filepath.Rel(h.cwd, filepath.Dir(dir))
-		if err != nil {
-			fullFileName = f.File
-		} else {
-			fullFileName = filepath.Join(relativeDir, file)
-		}
+	// Collapse consecutive repeats into the entry already stored, in each of the files
+	var key string
+	if h.coalescer != nil {
+		key = coalesceKey(r.Level, r.Message, h.flattenAttrs(r))
+	}
+	var errs []error
+	repeated, unlockCoalescer, err := h.coalesce(key, r.Time)
+	errs = append(errs, err)
+	outRepeated := repeated
+	unlockMain := func() {}
+	if h.main != nil {
+		outRepeated, unlockMain, err = h.main.coalesce(key, r.Time)
+		errs = append(errs, err)
+	}
+	if repeated && outRepeated {
+		return errors.Join(errs...)
+	}
 
-		undecoratedLocation = fmt.Sprintf("%s:%d", fullFileName, f.Line)
-		decoratedLocation = color.BlueString(undecoratedLocation)
+	// Set minimum length of 5 chars for the level
+	level := h.levelName(r.Level)
 
+	// The location of the log call
+	var location, fp string
+	var source *slog.Source
+	if r.PC != 0 {
+		s := h.callSite(r.PC)
+		fp = s.fingerprint(r.Message)
+		if !h.opts.NoSource {
+			location = s.location
+			source = &slog.Source{Function: s.frame.Function, File: s.file, Line: s.frame.Line}
+		}
+	} else {
+		fp = fingerprint("", r.Message)
 	}
 
-	// *******************************************
-	// timestamp
-	// *******************************************
-	bufColor = append(bufColor, logTimeColored...)
-	bufColor = append(bufColor, ' ')
-
-	bufPlain = append(bufPlain, logTime...)
+	// The plain line is stored in the database
+	bufPlain = r.Time.AppendFormat(bufPlain, time.TimeOnly)
 	bufPlain = append(bufPlain, ' ')
 
-	// *******************************************
-	// level
-	// *******************************************
-	bufColor = append(bufColor, coloredLevel...)
-	bufColor = append(bufColor, ' ')
-	if len(level) < 5 {
-		bufColor = append(bufColor, ' ')
-	}
-
 	bufPlain = append(bufPlain, level...)
 	bufPlain = append(bufPlain, ' ')
 	if len(level) < 5 {
 		bufPlain = append(bufPlain, ' ')
 	}
 
-	// *******************************************
-	// location
-	// *******************************************
-
-	bufColor = append(bufColor, decoratedLocation...)
-	bufColor = append(bufColor, ' ')
+	if source != nil {
+		bufPlain = append(bufPlain, location...)
+		bufPlain = append(bufPlain, ' ')
+	}
 
-	bufPlain = append(bufPlain, undecoratedLocation...)
+	bufPlain = append(bufPlain, r.Message...)
 	bufPlain = append(bufPlain, ' ')
 
-	// *******************************************
-	// message
-	// *******************************************
+	// The attributes are rendered once, for the database and the console
+	var keysBuf [32]int
+	attrsStart := len(bufPlain)
+	bufPlain, keys := h.appendAttrs(bufPlain, keysBuf[:0], r)
+	for i := range keys {
+		keys[i] -= attrsStart
+	}
+	attrs := bufPlain[attrsStart:]
 
-	bufColor = append(bufColor, r.Message...)
-	bufColor = append(bufColor, ' ')
+	bufPlain = append(bufPlain, '\n')
 
-	bufPlain = append(bufPlain, r.Message...)
-	bufPlain = append(bufPlain, ' ')
+	// Print the colored line to the console, or leave it to the console goroutine
+	line := consoleLine{time: r.Time, level: r.Level, name: level, location: location, message: r.Message, stack: stack}
+	switch {
+	case outRepeated:
+	case out.asyncConsole != nil:
+		out.asyncConsole.enqueue(consoleJob{h: out, line: line, attrs: bytes.Clone(attrs), keys: slices.Clone(keys)})
+	default:
+		bufColor = out.appendConsole(bufColor, &line, attrs, keys)
+		out.console.Write(bufColor)
+	}
 
-	// *******************************************
-	// *******************************************
+	entry := Entry{
+		Time:    r.Time,
+		Level:   r.Level,
+		Content: string(bufPlain),
+		Message: r.Message,
+		Source:  source,
+		Stack:   stack,
+		Blobs:   payloads,
 
-	// Handle state from WithGroup and WithAttrs.
-	goas := h.goas
-	if r.NumAttrs() == 0 {
-		// If the record has no Attrs, remove groups at the end of the list; they are empty.
-		for len(goas) > 0 && goas[len(goas)-1].group != "" {
-			goas = goas[:len(goas)-1]
-		}
+		Fingerprint: fp,
 	}
 
-	for _, goa := range goas {
-		if goa.group != "" {
-			bufColor = fmt.Appendf(bufColor, "%s ", goa.group)
-		} else {
-			for _, a := range goa.attrs {
-				bufColor = h.appendAttr(bufColor, a, greyColor)
+	if len(out.opts.Sinks) > 0 || h.storeAttrs {
+		entry.Attrs = h.flattenAttrs(r)
+	}
+
+	// Forward the entry to the additional sinks, reporting the errors after storing it
+	if !outRepeated {
+		for _, sink := range out.opts.Sinks {
+			// The sinks get a copy, so the entry stays on the stack without them
+			e := entry
+			if err := sink.Send(&e); err != nil {
+				out.metrics.observeSinkError()
+				errs = append(errs, err)
 			}
 		}
 	}
 
-	r.Attrs(func(a slog.Attr) bool {
-		bufColor = h.appendAttr(bufColor, a, greyColor)
-		return true
-	})
+	if !repeated {
+		errs = append(errs, h.write(c, entry, unlockCoalescer))
+	}
+	if h.main != nil && !outRepeated {
+		errs = append(errs, h.main.write(c, entry, unlockMain))
+	}
 
-	bufColor = append(bufColor, '\n')
-	bufPlain = append(bufPlain, '\n')
+	return errors.Join(errs...)
+}
 
-	// Print the colored buffer to standard output as a normal log
-	// fmt.Println(string(bufColor))
-	os.Stdout.Write(bufColor)
+// coalesce reports whether the record with the given key repeats the last entry
+// stored, counting it in the entry if so. Otherwise the coalescer stays locked
+// until the returned function is called, so the repeats are counted on the right
+// entry.
+func (h *SQLogger) coalesce(key string, t time.Time) (bool, func(), error) {
+	c := h.coalescer
+	if c == nil {
+		return false, func() {}, nil
+	}
+	c.mu.Lock()
+	if c.repeat(key, h.store.CurrentName(), t, h.console) {
+		err := h.store.(Repeater).RepeatLast(c.count, t)
+		c.mu.Unlock()
+		return true, nil, err
+	}
+	return false, c.mu.Unlock, nil
+}
 
-	// Insert the undecorated buffer into the log database
-	stmt, err := h.db.Prepare("insert into entries (epoch_secs, nanos, level, content) values(?, ?, ?, ?)")
-	if err != nil {
-		return err
+// write stores an entry according to the storage policy of its level, unlocking the
+// coalescer once it is inserted, and rotates when the live file is full.
+func (h *SQLogger) write(c context.Context, entry Entry, unlockCoalescer func()) error {
+	policy := h.levelStorage[entry.Level]
+	if policy.Skip {
+		if h.coalescer != nil {
+			h.coalescer.key = ""
+		}
+		unlockCoalescer()
+		return nil
 	}
-	defer stmt.Close()
 
-	result, err := stmt.Exec(r.Time.Unix(), r.Time.Nanosecond(), r.Level, string(bufPlain))
-	if err != nil {
-		return fmt.Errorf("inserting log record: %w", err)
+	// Insert the undecorated buffer into the log database. The context of the record
+	// limits the insert only with a write timeout, so the entries logged at the end
+	// of a canceled request are still stored.
+	ctx := context.Background()
+	if h.opts.WriteTimeout > 0 {
+		if c != nil {
+			ctx = c
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.opts.WriteTimeout)
+		defer cancel()
+	}
+	inserted, err := h.insert(ctx, entry)
+	if err != nil && h.coalescer != nil {
+		// Repeats can not be counted on an entry which was not inserted
+		h.coalescer.key = ""
 	}
+	unlockCoalescer()
+	if inserted == 0 {
+		return err
+	}
+	errs := []error{err}
 
-	// Check if the current log file has reached the maximum number of entries, and rotate the log if so
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("retrieving last insert id: %w", err)
+	if policy.Sync || (h.opts.SyncOnLevel != nil && entry.Level >= h.opts.SyncOnLevel.Level()) {
+		errs = append(errs, h.Sync())
 	}
-	h.lastInsertId = id
 
-	if h.lastInsertId >= defaultMaxSizeLiveLog {
-		h.Rotate()
+	// Rotate when the live file reaches the maximum number of entries. The entries
+	// are counted again under the lock, as concurrent handlers may have rotated.
+	n := h.rotation.entries.Add(int64(inserted))
+	h.metrics.observeRows(n)
+
+	maxEntries := int64(h.opts.MaxEntries)
+	if n >= maxEntries && h.rotation.mu.TryLock() {
+		if h.rotation.entries.Load() >= maxEntries {
+			h.rotate()
+		}
+		h.rotation.mu.Unlock()
 	}
 
-	return nil
+	return errors.Join(errs...)
+}
+
+// relativeFile returns the path of a source file relative to the working directory.
+func (h *SQLogger) relativeFile(path string) string {
+	dir, file := filepath.Split(path)
+
+	// Trim the root directory prefix to get the relative directory of the source file
+	relativeDir, err := filepath.Rel(h.cwd, filepath.Dir(dir))
+	if err != nil {
+		return path
+	}
+	return filepath.Join(relativeDir, file)
 }
 
 func (h *SQLogger) withGroupOrAttrs(goa groupOrAttrs) *SQLogger {
 	h2 := *h
 	h2.goas = make([]groupOrAttrs, len(h.goas)+1)
 	copy(h2.goas, h.goas)
+	if goa.group != "" {
+		h2.prefix = h.prefix + goa.group + "."
+	} else {
+		goa.prefix = h.prefix
+	}
 	h2.goas[len(h2.goas)-1] = goa
+	if len(h.opts.LevelRules) > 0 {
+		h2.rule = h2.levelRule()
+	}
 	return &h2
 }
 
@@ -426,6 +841,10 @@ func (h *SQLogger) WithAttrs(attrs []slog.Attr) slog.Handler {
 	if len(attrs) == 0 {
 		return h
 	}
+	if h.opts.Redaction != nil {
+		attrs, _ = h.opts.Redaction.redactAttrs(attrs)
+	}
+	attrs, _ = truncateAttrs(attrs, "", h.opts.MaxAttrBytes, h.opts.MaxBinaryAttrBytes, nil)
 	return h.withGroupOrAttrs(groupOrAttrs{attrs: attrs})
 }
 
@@ -436,53 +855,219 @@ func (h *SQLogger) WithGroup(name string) slog.Handler {
 	return h.withGroupOrAttrs(groupOrAttrs{group: name})
 }
 
-func (h *SQLogger) Close() {
-	h.db.Close()
-	return
+// Close flushes and closes the store. With the default store, the WAL is
+// checkpointed into the database file so it can be copied right away.
+func (h *SQLogger) Close() error {
+	var errs []error
+
+	// The named handlers may still copy their records to this one
+	if h.named != nil && !h.isNamed {
+		errs = append(errs, h.named.close())
+	}
+
+	if c := h.coalescer; c != nil {
+		c.mu.Lock()
+		c.flushNotice(h.console)
+		c.mu.Unlock()
+	}
+
+	// The lines queued are printed before returning
+	if h.asyncConsole != nil {
+		h.asyncConsole.close()
+	}
+
+	if err := h.flushRetries(); err != nil {
+		errs = append(errs, err)
+	}
+
+	// The sinks are shared with the named handlers
+	if !h.isNamed {
+		for _, sink := range h.opts.Sinks {
+			if err := sink.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if err := h.store.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("closing log store: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// consoleLine is what the console line of a record is rendered from, with its
+// attributes.
+type consoleLine struct {
+	time     time.Time
+	level    slog.Level
+	name     string // the name of the level
+	location string
+	message  string
+	stack    []slog.Source
 }
 
-func (h *SQLogger) appendAttr(buf []byte, a slog.Attr, keyColor *color.Color) []byte {
+// appendConsole renders the colored line of a record for the console, with the
+// level name, the location of the log call, if any, and the stack trace. The
+// attributes are rendered once, plain, and their keys are colored with the start
+// and end offsets in keys.
+func (h *SQLogger) appendConsole(buf []byte, line *consoleLine, attrs []byte, keys []int) []byte {
+	// *******************************************
+	// timestamp
+	// *******************************************
+	if !h.opts.Layout.HideTime {
+		consoleTimeFormat := h.opts.Layout.TimeFormat
+		if consoleTimeFormat == "" {
+			consoleTimeFormat = time.TimeOnly
+		}
+		buf = append(buf, h.palette.time.start...)
+		buf = line.time.AppendFormat(buf, consoleTimeFormat)
+		buf = append(buf, h.palette.time.end...)
+		buf = append(buf, ' ')
+	}
+
+	// *******************************************
+	// level
+	// *******************************************
+	if e, ok := h.palette.levels[line.level]; ok {
+		buf = e.wrap(buf, line.name)
+	} else {
+		buf = append(buf, line.name...)
+	}
+	buf = append(buf, ' ')
+	if len(line.name) < 5 && !h.opts.Layout.NoLevelPadding {
+		buf = append(buf, ' ')
+	}
+
+	// *******************************************
+	// location
+	// *******************************************
+	if line.location != "" && !h.opts.Layout.HideSource {
+		buf = h.palette.source.wrap(buf, line.location)
+		buf = append(buf, ' ')
+	}
+
+	// *******************************************
+	// message
+	// *******************************************
+	buf = append(buf, line.message...)
+	buf = append(buf, ' ')
+
+	// *******************************************
+	// attributes
+	// *******************************************
+	key := h.palette.key
+	if key.start == "" {
+		buf = append(buf, attrs...)
+	} else {
+		last := 0
+		for i := 0; i < len(keys); i += 2 {
+			buf = append(buf, attrs[last:keys[i]]...)
+			buf = append(buf, key.start...)
+			buf = append(buf, attrs[keys[i]:keys[i+1]]...)
+			buf = append(buf, key.end...)
+			last = keys[i+1]
+		}
+		buf = append(buf, attrs[last:]...)
+	}
+
+	buf = append(buf, '\n')
+
+	return h.appendStack(buf, line.stack)
+}
+
+// appendAttrs renders the attributes of the handler and of the record, with the
+// keys qualified by their groups, from WithGroup or from group values, like
+// req.header.host="example.com". The offsets of the keys are appended to keys.
+func (h *SQLogger) appendAttrs(buf []byte, keys []int, r slog.Record) ([]byte, []int) {
+	var prefix [64]byte
+	for _, goa := range h.goas {
+		for _, a := range goa.attrs {
+			buf, keys = appendAttr(buf, keys, append(prefix[:0], goa.prefix...), a)
+		}
+	}
+
+	recordPrefix := append(prefix[:0], h.prefix...)
+	r.Attrs(func(a slog.Attr) bool {
+		buf, keys = appendAttr(buf, keys, recordPrefix, a)
+		return true
+	})
+
+	return buf, keys
+}
+
+// appendAttr renders an attribute with its key qualified by prefix.
+func appendAttr(buf []byte, keys []int, prefix []byte, a slog.Attr) ([]byte, []int) {
 	// Resolve the Attr's value before doing anything else.
 	a.Value = a.Value.Resolve()
 	// Ignore empty Attrs.
 	if a.Equal(slog.Attr{}) {
-		return buf
+		return buf, keys
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		// The attributes of a group with an empty key are inlined
+		if a.Key != "" {
+			prefix = append(append(prefix, a.Key...), '.')
+		}
+		// Empty groups are ignored
+		for _, ga := range a.Value.Group() {
+			buf, keys = appendAttr(buf, keys, prefix, ga)
+		}
+		return buf, keys
 	}
+
 	switch a.Value.Kind() {
 	case slog.KindString:
-
-		buf = fmt.Appendf(buf, "%s%q ", keyColor.Sprint(a.Key+"="), a.Value.String())
+		buf, keys = appendKey(buf, keys, prefix, a.Key)
+		buf = strconv.AppendQuote(buf, a.Value.String())
 
 	case slog.KindTime:
 		// Write times in a standard way, without the monotonic time.
-		if a.Key == slog.TimeKey {
-			buf = fmt.Appendf(buf, "%s ", a.Value.Time().Format(time.RFC3339Nano))
-			break
+		if a.Key != slog.TimeKey {
+			buf, keys = appendKey(buf, keys, prefix, a.Key)
 		}
+		buf = a.Value.Time().AppendFormat(buf, time.RFC3339Nano)
 
-		buf = fmt.Appendf(buf, "%s%s ", keyColor.Sprint(a.Key+"="), a.Value.Time().Format(time.RFC3339Nano))
-	case slog.KindGroup:
-		attrs := a.Value.Group()
-		// Ignore empty groups.
-		if len(attrs) == 0 {
-			return buf
+	default:
+		if b, ok := binaryValue(a.Value); ok {
+			buf, keys = appendKey(buf, keys, prefix, a.Key)
+			buf = hex.AppendEncode(buf, b)
+			break
+		}
+		if a.Key != slog.LevelKey {
+			buf, keys = appendKey(buf, keys, prefix, a.Key)
 		}
-		for _, ga := range attrs {
+		buf = appendValue(buf, a.Value)
+	}
+	return append(buf, ' '), keys
+}
 
-			if a.Key != "" {
-				buf = fmt.Appendf(buf, "%s.", a.Key)
-			}
+// appendKey renders the qualified key of an attribute, recording its span.
+func appendKey(buf []byte, keys []int, prefix []byte, key string) ([]byte, []int) {
+	keys = append(keys, len(buf))
+	buf = append(buf, prefix...)
+	buf = append(buf, key...)
+	buf = append(buf, '=')
+	return buf, append(keys, len(buf))
+}
 
-			buf = h.appendAttr(buf, ga, keyColor)
-		}
+// appendValue renders a value as its String method does, without allocating for
+// the basic kinds.
+func appendValue(buf []byte, v slog.Value) []byte {
+	switch v.Kind() {
+	case slog.KindInt64:
+		return strconv.AppendInt(buf, v.Int64(), 10)
+	case slog.KindUint64:
+		return strconv.AppendUint(buf, v.Uint64(), 10)
+	case slog.KindFloat64:
+		return strconv.AppendFloat(buf, v.Float64(), 'g', -1, 64)
+	case slog.KindBool:
+		return strconv.AppendBool(buf, v.Bool())
+	case slog.KindDuration:
+		return append(buf, v.Duration().String()...)
 	default:
-		if a.Key == slog.LevelKey {
-			buf = fmt.Appendf(buf, "%s ", a.Value.String())
-			break
-		}
-		buf = fmt.Appendf(buf, "%s%s ", keyColor.Sprint(a.Key+"="), a.Value)
+		return fmt.Append(buf, v.Any())
 	}
-	return buf
 }
 
 var bufPool = sync.Pool{
@@ -0,0 +1,125 @@
+package sqlogger_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/hesusruiz/sqlogger"
+)
+
+// discardStore accepts the entries without writing them, to measure the handler alone.
+type discardStore struct {
+	n int64
+}
+
+func (s *discardStore) Open() error { return nil }
+
+func (s *discardStore) Insert(e sqlogger.Entry) (int64, error) {
+	s.n++
+	return s.n, nil
+}
+
+func (s *discardStore) Rotate() error { return nil }
+
+func (s *discardStore) CurrentName() string { return "discard" }
+
+func (s *discardStore) Query(ctx context.Context, q sqlogger.Query) ([]sqlogger.Entry, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s *discardStore) Close() error { return nil }
+
+var benchCases = []struct {
+	name string
+	opts sqlogger.Options
+	with func(l *slog.Logger) *slog.Logger
+	log  func(l *slog.Logger)
+}{
+	{
+		name: "Message",
+		opts: sqlogger.Options{NoSource: true},
+		log: func(l *slog.Logger) {
+			l.Info("request served")
+		},
+	},
+	{
+		name: "Attrs",
+		opts: sqlogger.Options{NoSource: true},
+		log: func(l *slog.Logger) {
+			l.Info("request served", "method", "GET", "status", 200, "bytes", 5120,
+				"elapsed", 42*time.Millisecond, "cached", false)
+		},
+	},
+	{
+		name: "Groups",
+		opts: sqlogger.Options{NoSource: true},
+		with: func(l *slog.Logger) *slog.Logger {
+			return l.With("service", "orders").WithGroup("http")
+		},
+		log: func(l *slog.Logger) {
+			l.Info("request served",
+				slog.Group("req", "method", "GET", slog.Group("header", "host", "example.com", "accept", "*/*")),
+				slog.Int("status", 200))
+		},
+	},
+	{
+		name: "Source",
+		log: func(l *slog.Logger) {
+			l.Info("request served", "method", "GET", "status", 200)
+		},
+	},
+	{
+		name: "Color",
+		opts: sqlogger.Options{NoSource: true, Color: sqlogger.ColorAlways},
+		log: func(l *slog.Logger) {
+			l.Info("request served", "method", "GET", "status", 200)
+		},
+	},
+}
+
+// BenchmarkHandle measures the cost of a record in the handler, without the store.
+func BenchmarkHandle(b *testing.B) {
+	for _, bc := range benchCases {
+		b.Run(bc.name, func(b *testing.B) {
+			opts := bc.opts
+			opts.Console = io.Discard
+			opts.Store = &discardStore{}
+			benchmarkLog(b, &opts, bc.with, bc.log)
+		})
+	}
+}
+
+// BenchmarkHandleSQLite measures the cost of a record with the default store.
+func BenchmarkHandleSQLite(b *testing.B) {
+	for _, bc := range benchCases {
+		b.Run(bc.name, func(b *testing.B) {
+			opts := bc.opts
+			opts.Console = io.Discard
+			opts.Dir = b.TempDir()
+			benchmarkLog(b, &opts, bc.with, bc.log)
+		})
+	}
+}
+
+func benchmarkLog(b *testing.B, opts *sqlogger.Options, with func(l *slog.Logger) *slog.Logger, log func(l *slog.Logger)) {
+	// The rotations are left out
+	opts.MaxEntries = 1 << 30
+	h, err := sqlogger.NewSQLogger(opts)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer h.Close()
+	l := slog.New(h)
+	if with != nil {
+		l = with(l)
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		log(l)
+	}
+}
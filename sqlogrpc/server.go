@@ -0,0 +1,405 @@
+// Package sqlogrpc serves the entries of a rotation set over gRPC, so a central
+// dashboard can pull the logs of many services from their local files:
+//
+//	rd, err := sqlogger.OpenSet("/var/log/orders")
+//	...
+//	srv, err := sqlogrpc.NewServer(rd, &sqlogrpc.Options{Token: token})
+//	...
+//	gs := grpc.NewServer(grpc.Creds(tlsCreds))
+//	sqlogpb.RegisterLogServiceServer(gs, srv)
+//	gs.Serve(lis)
+//
+// The clients use the generated sqlogpb.LogServiceClient, with the token:
+//
+//	conn, err := grpc.NewClient(addr,
+//		grpc.WithTransportCredentials(tlsCreds),
+//		grpc.WithPerRPCCredentials(sqlogrpc.BearerToken(token)))
+//	client := sqlogpb.NewLogServiceClient(conn)
+package sqlogrpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/hesusruiz/sqlogger"
+	"github.com/hesusruiz/sqlogger/sqlogrpc/sqlogpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultPageSize     = 100
+	maxPageSize         = 1000
+	defaultSearchLimit  = 1000
+	defaultPollInterval = time.Second
+)
+
+// Options configures a Server. Either Token or Authorize must be set.
+type Options struct {
+	// Token is the secret the clients must send in the "authorization" metadata
+	// as "Bearer <token>", like with BearerToken.
+	Token string
+
+	// Authorize, if set, is called instead with the context of every call, to
+	// check the credentials of the client, like its TLS certificate. The error
+	// is returned to the client as Unauthenticated, unless it is a gRPC status.
+	Authorize func(ctx context.Context) error
+
+	// PollInterval is how often StreamTail looks for new entries. One second if zero.
+	PollInterval time.Duration
+
+	// LevelNames are the names of additional levels, like in sqlogger.Options.
+	LevelNames map[slog.Leveler]string
+}
+
+// Server implements sqlogpb.LogServiceServer with a Reader, which is refreshed on
+// every call to see the files created by the rotations.
+type Server struct {
+	sqlogpb.UnimplementedLogServiceServer
+
+	rd   *sqlogger.Reader
+	opts Options
+}
+
+var _ sqlogpb.LogServiceServer = (*Server)(nil)
+
+// NewServer returns a server of the entries read by rd. The Reader is not closed
+// by the server.
+func NewServer(rd *sqlogger.Reader, opts *Options) (*Server, error) {
+	s := &Server{rd: rd}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.Token == "" && s.opts.Authorize == nil {
+		return nil, fmt.Errorf("a token or an authorize function is required")
+	}
+	if s.opts.PollInterval <= 0 {
+		s.opts.PollInterval = defaultPollInterval
+	}
+	return s, nil
+}
+
+// ListEntries returns a page of the entries of a time range.
+func (s *Server) ListEntries(ctx context.Context, req *sqlogpb.ListEntriesRequest) (*sqlogpb.ListEntriesResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	pageSize := int(req.GetPageSize())
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "negative page size")
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+
+	var c cursor
+	if req.GetPageToken() != "" {
+		var err error
+		if c, err = parseCursor(req.GetPageToken()); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page token")
+		}
+	}
+
+	q := sqlogger.Query{Since: timeOf(req.GetSince()), Until: timeOf(req.GetUntil())}
+	// One more entry tells if there is a next page
+	q = c.query(q, pageSize+1)
+
+	entries, err := s.read(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	entries = c.skip(entries)
+
+	resp := &sqlogpb.ListEntriesResponse{}
+	if len(entries) > pageSize {
+		entries = entries[:pageSize]
+		c.advance(entries)
+		resp.NextPageToken = c.String()
+	}
+	resp.Entries = s.entries(entries)
+
+	return resp, nil
+}
+
+// Search returns the entries selected by a filter.
+func (s *Server) Search(ctx context.Context, req *sqlogpb.SearchRequest) (*sqlogpb.SearchResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	q, err := query(req.GetFilter())
+	if err != nil {
+		return nil, err
+	}
+	switch limit := req.GetLimit(); {
+	case limit < 0:
+		return nil, status.Error(codes.InvalidArgument, "negative limit")
+	case limit == 0:
+		q.Limit = defaultSearchLimit
+	default:
+		q.Limit = int(limit)
+	}
+
+	entries, err := s.read(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlogpb.SearchResponse{Entries: s.entries(entries)}, nil
+}
+
+// StreamTail polls the files for the entries selected by the filter, sending them
+// as they are found, until the client cancels the call or the end of the time
+// range of the filter is reached.
+func (s *Server) StreamTail(req *sqlogpb.StreamTailRequest, stream sqlogpb.LogService_StreamTailServer) error {
+	ctx := stream.Context()
+	if err := s.authorize(ctx); err != nil {
+		return err
+	}
+
+	q, err := query(req.GetFilter())
+	if err != nil {
+		return err
+	}
+	c := cursor{time: q.Since}
+	if c.time.IsZero() {
+		c.time = time.Now()
+	}
+
+	ticker := time.NewTicker(s.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		entries, err := s.read(ctx, c.query(q, 0))
+		if err != nil {
+			return err
+		}
+		entries = c.skip(entries)
+		for _, e := range s.entries(entries) {
+			if err := stream.Send(e); err != nil {
+				return err
+			}
+		}
+		c.advance(entries)
+
+		if !q.Until.IsZero() && time.Now().After(q.Until) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// authorize checks the credentials of the client of a call.
+func (s *Server) authorize(ctx context.Context) error {
+	if s.opts.Authorize != nil {
+		err := s.opts.Authorize(ctx)
+		if _, ok := status.FromError(err); err != nil && !ok {
+			return status.Error(codes.Unauthenticated, err.Error())
+		}
+		return err
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	want := []byte("Bearer " + s.opts.Token)
+	for _, got := range md.Get("authorization") {
+		if subtle.ConstantTimeCompare([]byte(got), want) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid or missing token")
+}
+
+// read refreshes the files of the Reader and queries them.
+func (s *Server) read(ctx context.Context, q sqlogger.Query) ([]sqlogger.Entry, error) {
+	if err := s.rd.Refresh(); err != nil {
+		return nil, status.Errorf(codes.Internal, "reading the log files: %v", err)
+	}
+	entries, err := s.rd.Query(ctx, q)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, status.FromContextError(ctxErr).Err()
+		}
+		return nil, status.Errorf(codes.Internal, "querying the log files: %v", err)
+	}
+	return entries, nil
+}
+
+// entries converts the entries to their protobuf messages.
+func (s *Server) entries(entries []sqlogger.Entry) []*sqlogpb.Entry {
+	msgs := make([]*sqlogpb.Entry, len(entries))
+	for i, e := range entries {
+		msg := &sqlogpb.Entry{
+			Time:        timestamppb.New(e.Time),
+			Level:       int32(e.Level),
+			LevelName:   s.levelName(e.Level),
+			Content:     e.Content,
+			RepeatCount: e.RepeatCount,
+			Source:      source(e.Source),
+			File:        e.File,
+			Id:          e.ID,
+		}
+		if !e.LastTime.IsZero() {
+			msg.LastTime = timestamppb.New(e.LastTime)
+		}
+		for _, frame := range e.Stack {
+			msg.Stack = append(msg.Stack, source(&frame))
+		}
+		msgs[i] = msg
+	}
+	return msgs
+}
+
+func (s *Server) levelName(l slog.Level) string {
+	for leveler, name := range s.opts.LevelNames {
+		if leveler.Level() == l {
+			return name
+		}
+	}
+	return l.String()
+}
+
+func source(src *slog.Source) *sqlogpb.Source {
+	if src == nil {
+		return nil
+	}
+	return &sqlogpb.Source{Function: src.Function, File: src.File, Line: int32(src.Line)}
+}
+
+// query converts a filter to a Query.
+func query(f *sqlogpb.Filter) (sqlogger.Query, error) {
+	q := sqlogger.Query{
+		Since:      timeOf(f.GetSince()),
+		Until:      timeOf(f.GetUntil()),
+		Contains:   f.GetContains(),
+		SourceFile: f.GetSourceFile(),
+	}
+	if f != nil && f.MinLevel != nil {
+		q.MinLevel = slog.Level(f.GetMinLevel())
+	}
+
+	for key, v := range f.GetAttrs() {
+		if q.Attrs == nil {
+			q.Attrs = map[string]any{}
+		}
+		switch kind := v.GetKind().(type) {
+		case *structpb.Value_StringValue:
+			q.Attrs[key] = kind.StringValue
+		case *structpb.Value_BoolValue:
+			q.Attrs[key] = kind.BoolValue
+		case *structpb.Value_NumberValue:
+			// Whole numbers match the integer attributes
+			if n := kind.NumberValue; n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+				q.Attrs[key] = int64(n)
+			} else {
+				q.Attrs[key] = n
+			}
+		default:
+			return q, status.Errorf(codes.InvalidArgument, "attribute %q must be a string, a number or a boolean", key)
+		}
+	}
+
+	return q, nil
+}
+
+// timeOf returns the time of a timestamp, or the zero time if it is not set.
+func timeOf(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// cursor is a position in the log, after the entries before time and the first
+// seen entries at time, as several entries can have the same time.
+type cursor struct {
+	time time.Time
+	seen int
+}
+
+// query returns q restricted to the entries from the cursor on, with room for
+// limit entries after the skipped ones, or no limit if zero.
+func (c cursor) query(q sqlogger.Query, limit int) sqlogger.Query {
+	if c.time.After(q.Since) {
+		q.Since = c.time
+	}
+	if limit > 0 {
+		q.Limit = c.seen + limit
+	}
+	return q
+}
+
+// skip drops the entries already seen from the result of a query.
+func (c cursor) skip(entries []sqlogger.Entry) []sqlogger.Entry {
+	i := 0
+	for i < c.seen && i < len(entries) && entries[i].Time.Equal(c.time) {
+		i++
+	}
+	return entries[i:]
+}
+
+// advance moves the cursor after the entries.
+func (c *cursor) advance(entries []sqlogger.Entry) {
+	for _, e := range entries {
+		if e.Time.Equal(c.time) {
+			c.seen++
+		} else {
+			c.time, c.seen = e.Time, 1
+		}
+	}
+}
+
+// String encodes the cursor as a page token.
+func (c cursor) String() string {
+	text := fmt.Sprintf("%d.%d", c.time.UnixNano(), c.seen)
+	return base64.RawURLEncoding.EncodeToString([]byte(text))
+}
+
+func parseCursor(token string) (cursor, error) {
+	text, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor{}, err
+	}
+	var nanos int64
+	var c cursor
+	if _, err := fmt.Sscanf(string(text), "%d.%d", &nanos, &c.seen); err != nil {
+		return cursor{}, err
+	}
+	if c.seen < 0 {
+		return cursor{}, errors.New("negative count")
+	}
+	c.time = time.Unix(0, nanos)
+	return c, nil
+}
+
+// BearerToken returns the credentials sending the token of a Server on every call.
+// They require a secure connection, as the token would be sent in the clear.
+func BearerToken(token string) credentials.PerRPCCredentials {
+	return bearerToken(token)
+}
+
+type bearerToken string
+
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t bearerToken) RequireTransportSecurity() bool {
+	return true
+}
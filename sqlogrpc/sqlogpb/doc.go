@@ -0,0 +1,5 @@
+// Package sqlogpb contains the protobuf messages and the gRPC service generated
+// from sqlogger.proto, with buf and the protoc-gen-go and protoc-gen-go-grpc plugins.
+package sqlogpb
+
+//go:generate buf generate
@@ -0,0 +1,700 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: sqlogger.proto
+
+// The API to read the log entries stored by sqlogger in a remote service.
+
+package sqlogpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Entry struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Time  *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	// The slog level, like 0 for INFO or 8 for ERROR, and its name.
+	Level     int32  `protobuf:"varint,2,opt,name=level,proto3" json:"level,omitempty"`
+	LevelName string `protobuf:"bytes,3,opt,name=level_name,json=levelName,proto3" json:"level_name,omitempty"`
+	// The rendered log line, without color decoration.
+	Content string `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`
+	// The number of consecutive times the entry was logged, and the time of the
+	// last one, when repeats are coalesced.
+	RepeatCount int64                  `protobuf:"varint,5,opt,name=repeat_count,json=repeatCount,proto3" json:"repeat_count,omitempty"`
+	LastTime    *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=last_time,json=lastTime,proto3" json:"last_time,omitempty"`
+	// The location of the log call, if recorded.
+	Source *Source `protobuf:"bytes,7,opt,name=source,proto3" json:"source,omitempty"`
+	// The stack trace of the error logged with the entry, innermost frame first.
+	Stack []*Source `protobuf:"bytes,8,rep,name=stack,proto3" json:"stack,omitempty"`
+	// The database file containing the entry, and its id in the file.
+	File          string `protobuf:"bytes,9,opt,name=file,proto3" json:"file,omitempty"`
+	Id            int64  `protobuf:"varint,10,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Entry) Reset() {
+	*x = Entry{}
+	mi := &file_sqlogger_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Entry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Entry) ProtoMessage() {}
+
+func (x *Entry) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlogger_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Entry.ProtoReflect.Descriptor instead.
+func (*Entry) Descriptor() ([]byte, []int) {
+	return file_sqlogger_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Entry) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Entry) GetLevel() int32 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+func (x *Entry) GetLevelName() string {
+	if x != nil {
+		return x.LevelName
+	}
+	return ""
+}
+
+func (x *Entry) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *Entry) GetRepeatCount() int64 {
+	if x != nil {
+		return x.RepeatCount
+	}
+	return 0
+}
+
+func (x *Entry) GetLastTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastTime
+	}
+	return nil
+}
+
+func (x *Entry) GetSource() *Source {
+	if x != nil {
+		return x.Source
+	}
+	return nil
+}
+
+func (x *Entry) GetStack() []*Source {
+	if x != nil {
+		return x.Stack
+	}
+	return nil
+}
+
+func (x *Entry) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *Entry) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type Source struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Function      string                 `protobuf:"bytes,1,opt,name=function,proto3" json:"function,omitempty"`
+	File          string                 `protobuf:"bytes,2,opt,name=file,proto3" json:"file,omitempty"`
+	Line          int32                  `protobuf:"varint,3,opt,name=line,proto3" json:"line,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Source) Reset() {
+	*x = Source{}
+	mi := &file_sqlogger_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Source) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Source) ProtoMessage() {}
+
+func (x *Source) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlogger_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Source.ProtoReflect.Descriptor instead.
+func (*Source) Descriptor() ([]byte, []int) {
+	return file_sqlogger_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Source) GetFunction() string {
+	if x != nil {
+		return x.Function
+	}
+	return ""
+}
+
+func (x *Source) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *Source) GetLine() int32 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+type Filter struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Since *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=since,proto3" json:"since,omitempty"`
+	Until *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=until,proto3" json:"until,omitempty"`
+	// Only entries with a level greater or equal than this, if set.
+	MinLevel   *int32 `protobuf:"varint,3,opt,name=min_level,json=minLevel,proto3,oneof" json:"min_level,omitempty"`
+	Contains   string `protobuf:"bytes,4,opt,name=contains,proto3" json:"contains,omitempty"`
+	SourceFile string `protobuf:"bytes,5,opt,name=source_file,json=sourceFile,proto3" json:"source_file,omitempty"`
+	// Only entries with all these attributes. Whole numbers match integer
+	// attributes.
+	Attrs         map[string]*structpb.Value `protobuf:"bytes,6,rep,name=attrs,proto3" json:"attrs,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Filter) Reset() {
+	*x = Filter{}
+	mi := &file_sqlogger_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Filter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Filter) ProtoMessage() {}
+
+func (x *Filter) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlogger_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Filter.ProtoReflect.Descriptor instead.
+func (*Filter) Descriptor() ([]byte, []int) {
+	return file_sqlogger_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Filter) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *Filter) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+func (x *Filter) GetMinLevel() int32 {
+	if x != nil && x.MinLevel != nil {
+		return *x.MinLevel
+	}
+	return 0
+}
+
+func (x *Filter) GetContains() string {
+	if x != nil {
+		return x.Contains
+	}
+	return ""
+}
+
+func (x *Filter) GetSourceFile() string {
+	if x != nil {
+		return x.SourceFile
+	}
+	return ""
+}
+
+func (x *Filter) GetAttrs() map[string]*structpb.Value {
+	if x != nil {
+		return x.Attrs
+	}
+	return nil
+}
+
+type ListEntriesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Since *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=since,proto3" json:"since,omitempty"`
+	Until *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=until,proto3" json:"until,omitempty"`
+	// The maximum number of entries of the page, 100 by default.
+	PageSize int32 `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// The next_page_token of the previous page, empty for the first one.
+	PageToken     string `protobuf:"bytes,4,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListEntriesRequest) Reset() {
+	*x = ListEntriesRequest{}
+	mi := &file_sqlogger_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListEntriesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEntriesRequest) ProtoMessage() {}
+
+func (x *ListEntriesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlogger_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEntriesRequest.ProtoReflect.Descriptor instead.
+func (*ListEntriesRequest) Descriptor() ([]byte, []int) {
+	return file_sqlogger_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListEntriesRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *ListEntriesRequest) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+func (x *ListEntriesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListEntriesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ListEntriesResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Entries []*Entry               `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	// Empty on the last page.
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListEntriesResponse) Reset() {
+	*x = ListEntriesResponse{}
+	mi := &file_sqlogger_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListEntriesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEntriesResponse) ProtoMessage() {}
+
+func (x *ListEntriesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlogger_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEntriesResponse.ProtoReflect.Descriptor instead.
+func (*ListEntriesResponse) Descriptor() ([]byte, []int) {
+	return file_sqlogger_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListEntriesResponse) GetEntries() []*Entry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *ListEntriesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type SearchRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Filter *Filter                `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// The maximum number of entries returned, 1000 by default.
+	Limit         int32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_sqlogger_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlogger_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_sqlogger_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SearchRequest) GetFilter() *Filter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type SearchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*Entry               `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchResponse) Reset() {
+	*x = SearchResponse{}
+	mi := &file_sqlogger_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResponse) ProtoMessage() {}
+
+func (x *SearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlogger_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResponse.ProtoReflect.Descriptor instead.
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return file_sqlogger_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SearchResponse) GetEntries() []*Entry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type StreamTailRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The entries since filter.since are sent first, if set. Otherwise, only the
+	// entries logged after the call.
+	Filter        *Filter `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamTailRequest) Reset() {
+	*x = StreamTailRequest{}
+	mi := &file_sqlogger_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamTailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamTailRequest) ProtoMessage() {}
+
+func (x *StreamTailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlogger_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamTailRequest.ProtoReflect.Descriptor instead.
+func (*StreamTailRequest) Descriptor() ([]byte, []int) {
+	return file_sqlogger_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *StreamTailRequest) GetFilter() *Filter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+var File_sqlogger_proto protoreflect.FileDescriptor
+
+const file_sqlogger_proto_rawDesc = "" +
+	"\n" +
+	"\x0esqlogger.proto\x12\vsqlogger.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xde\x02\n" +
+	"\x05Entry\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x14\n" +
+	"\x05level\x18\x02 \x01(\x05R\x05level\x12\x1d\n" +
+	"\n" +
+	"level_name\x18\x03 \x01(\tR\tlevelName\x12\x18\n" +
+	"\acontent\x18\x04 \x01(\tR\acontent\x12!\n" +
+	"\frepeat_count\x18\x05 \x01(\x03R\vrepeatCount\x127\n" +
+	"\tlast_time\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\blastTime\x12+\n" +
+	"\x06source\x18\a \x01(\v2\x13.sqlogger.v1.SourceR\x06source\x12)\n" +
+	"\x05stack\x18\b \x03(\v2\x13.sqlogger.v1.SourceR\x05stack\x12\x12\n" +
+	"\x04file\x18\t \x01(\tR\x04file\x12\x0e\n" +
+	"\x02id\x18\n" +
+	" \x01(\x03R\x02id\"L\n" +
+	"\x06Source\x12\x1a\n" +
+	"\bfunction\x18\x01 \x01(\tR\bfunction\x12\x12\n" +
+	"\x04file\x18\x02 \x01(\tR\x04file\x12\x12\n" +
+	"\x04line\x18\x03 \x01(\x05R\x04line\"\xe1\x02\n" +
+	"\x06Filter\x120\n" +
+	"\x05since\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x120\n" +
+	"\x05until\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x05until\x12 \n" +
+	"\tmin_level\x18\x03 \x01(\x05H\x00R\bminLevel\x88\x01\x01\x12\x1a\n" +
+	"\bcontains\x18\x04 \x01(\tR\bcontains\x12\x1f\n" +
+	"\vsource_file\x18\x05 \x01(\tR\n" +
+	"sourceFile\x124\n" +
+	"\x05attrs\x18\x06 \x03(\v2\x1e.sqlogger.v1.Filter.AttrsEntryR\x05attrs\x1aP\n" +
+	"\n" +
+	"AttrsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12,\n" +
+	"\x05value\x18\x02 \x01(\v2\x16.google.protobuf.ValueR\x05value:\x028\x01B\f\n" +
+	"\n" +
+	"_min_level\"\xb4\x01\n" +
+	"\x12ListEntriesRequest\x120\n" +
+	"\x05since\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x120\n" +
+	"\x05until\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x05until\x12\x1b\n" +
+	"\tpage_size\x18\x03 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x04 \x01(\tR\tpageToken\"k\n" +
+	"\x13ListEntriesResponse\x12,\n" +
+	"\aentries\x18\x01 \x03(\v2\x12.sqlogger.v1.EntryR\aentries\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"R\n" +
+	"\rSearchRequest\x12+\n" +
+	"\x06filter\x18\x01 \x01(\v2\x13.sqlogger.v1.FilterR\x06filter\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\">\n" +
+	"\x0eSearchResponse\x12,\n" +
+	"\aentries\x18\x01 \x03(\v2\x12.sqlogger.v1.EntryR\aentries\"@\n" +
+	"\x11StreamTailRequest\x12+\n" +
+	"\x06filter\x18\x01 \x01(\v2\x13.sqlogger.v1.FilterR\x06filter2\xe5\x01\n" +
+	"\n" +
+	"LogService\x12P\n" +
+	"\vListEntries\x12\x1f.sqlogger.v1.ListEntriesRequest\x1a .sqlogger.v1.ListEntriesResponse\x12A\n" +
+	"\x06Search\x12\x1a.sqlogger.v1.SearchRequest\x1a\x1b.sqlogger.v1.SearchResponse\x12B\n" +
+	"\n" +
+	"StreamTail\x12\x1e.sqlogger.v1.StreamTailRequest\x1a\x12.sqlogger.v1.Entry0\x01B0Z.github.com/hesusruiz/sqlogger/sqlogrpc/sqlogpbb\x06proto3"
+
+var (
+	file_sqlogger_proto_rawDescOnce sync.Once
+	file_sqlogger_proto_rawDescData []byte
+)
+
+func file_sqlogger_proto_rawDescGZIP() []byte {
+	file_sqlogger_proto_rawDescOnce.Do(func() {
+		file_sqlogger_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_sqlogger_proto_rawDesc), len(file_sqlogger_proto_rawDesc)))
+	})
+	return file_sqlogger_proto_rawDescData
+}
+
+var file_sqlogger_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_sqlogger_proto_goTypes = []any{
+	(*Entry)(nil),                 // 0: sqlogger.v1.Entry
+	(*Source)(nil),                // 1: sqlogger.v1.Source
+	(*Filter)(nil),                // 2: sqlogger.v1.Filter
+	(*ListEntriesRequest)(nil),    // 3: sqlogger.v1.ListEntriesRequest
+	(*ListEntriesResponse)(nil),   // 4: sqlogger.v1.ListEntriesResponse
+	(*SearchRequest)(nil),         // 5: sqlogger.v1.SearchRequest
+	(*SearchResponse)(nil),        // 6: sqlogger.v1.SearchResponse
+	(*StreamTailRequest)(nil),     // 7: sqlogger.v1.StreamTailRequest
+	nil,                           // 8: sqlogger.v1.Filter.AttrsEntry
+	(*timestamppb.Timestamp)(nil), // 9: google.protobuf.Timestamp
+	(*structpb.Value)(nil),        // 10: google.protobuf.Value
+}
+var file_sqlogger_proto_depIdxs = []int32{
+	9,  // 0: sqlogger.v1.Entry.time:type_name -> google.protobuf.Timestamp
+	9,  // 1: sqlogger.v1.Entry.last_time:type_name -> google.protobuf.Timestamp
+	1,  // 2: sqlogger.v1.Entry.source:type_name -> sqlogger.v1.Source
+	1,  // 3: sqlogger.v1.Entry.stack:type_name -> sqlogger.v1.Source
+	9,  // 4: sqlogger.v1.Filter.since:type_name -> google.protobuf.Timestamp
+	9,  // 5: sqlogger.v1.Filter.until:type_name -> google.protobuf.Timestamp
+	8,  // 6: sqlogger.v1.Filter.attrs:type_name -> sqlogger.v1.Filter.AttrsEntry
+	9,  // 7: sqlogger.v1.ListEntriesRequest.since:type_name -> google.protobuf.Timestamp
+	9,  // 8: sqlogger.v1.ListEntriesRequest.until:type_name -> google.protobuf.Timestamp
+	0,  // 9: sqlogger.v1.ListEntriesResponse.entries:type_name -> sqlogger.v1.Entry
+	2,  // 10: sqlogger.v1.SearchRequest.filter:type_name -> sqlogger.v1.Filter
+	0,  // 11: sqlogger.v1.SearchResponse.entries:type_name -> sqlogger.v1.Entry
+	2,  // 12: sqlogger.v1.StreamTailRequest.filter:type_name -> sqlogger.v1.Filter
+	10, // 13: sqlogger.v1.Filter.AttrsEntry.value:type_name -> google.protobuf.Value
+	3,  // 14: sqlogger.v1.LogService.ListEntries:input_type -> sqlogger.v1.ListEntriesRequest
+	5,  // 15: sqlogger.v1.LogService.Search:input_type -> sqlogger.v1.SearchRequest
+	7,  // 16: sqlogger.v1.LogService.StreamTail:input_type -> sqlogger.v1.StreamTailRequest
+	4,  // 17: sqlogger.v1.LogService.ListEntries:output_type -> sqlogger.v1.ListEntriesResponse
+	6,  // 18: sqlogger.v1.LogService.Search:output_type -> sqlogger.v1.SearchResponse
+	0,  // 19: sqlogger.v1.LogService.StreamTail:output_type -> sqlogger.v1.Entry
+	17, // [17:20] is the sub-list for method output_type
+	14, // [14:17] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
+}
+
+func init() { file_sqlogger_proto_init() }
+func file_sqlogger_proto_init() {
+	if File_sqlogger_proto != nil {
+		return
+	}
+	file_sqlogger_proto_msgTypes[2].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_sqlogger_proto_rawDesc), len(file_sqlogger_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_sqlogger_proto_goTypes,
+		DependencyIndexes: file_sqlogger_proto_depIdxs,
+		MessageInfos:      file_sqlogger_proto_msgTypes,
+	}.Build()
+	File_sqlogger_proto = out.File
+	file_sqlogger_proto_goTypes = nil
+	file_sqlogger_proto_depIdxs = nil
+}
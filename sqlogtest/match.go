@@ -0,0 +1,146 @@
+package sqlogtest
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hesusruiz/sqlogger"
+)
+
+// Matcher selects the entries with a level, a message and some attributes.
+type Matcher struct {
+	level slog.Level
+	msg   string
+	attrs []slog.Attr
+}
+
+// HasEntry returns a Matcher selecting the entries with the given level, whose
+// message contains msgContains and which have all the attributes, given like the
+// arguments of slog.Logger.Info: key-value pairs or slog.Attr values. The keys are
+// qualified by their groups, like "req.method". Values are compared by kind and
+// value, so slog.Int("n", 1) matches a stored int64 or uint64 of 1.
+func HasEntry(level slog.Level, msgContains string, attrs ...any) Matcher {
+	r := slog.NewRecord(time.Time{}, level, msgContains, 0)
+	r.Add(attrs...)
+
+	m := Matcher{level: level, msg: msgContains}
+	r.Attrs(func(a slog.Attr) bool {
+		m.attrs = append(m.attrs, a)
+		return true
+	})
+	return m
+}
+
+// Match reports whether the entry is selected. Entries without a message, like
+// those read from the database, are matched by their content.
+func (m Matcher) Match(e sqlogger.Entry) bool {
+	if e.Level != m.level {
+		return false
+	}
+
+	text := e.Message
+	if text == "" {
+		text = e.Content
+	}
+	if !strings.Contains(text, m.msg) {
+		return false
+	}
+
+	for _, want := range m.attrs {
+		found := false
+		for _, a := range e.Attrs {
+			if a.Key == want.Key && normalized(a.Value) == normalized(want.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m Matcher) String() string {
+	s := fmt.Sprintf("%s %q", m.level, m.msg)
+	for _, a := range m.attrs {
+		s += " " + a.String()
+	}
+	return s
+}
+
+// Find returns the entries selected by m.
+func Find(entries []sqlogger.Entry, m Matcher) []sqlogger.Entry {
+	var found []sqlogger.Entry
+	for _, e := range entries {
+		if m.Match(e) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// AssertLogged fails the test if any of the matchers selects none of the entries.
+func AssertLogged(t testing.TB, entries []sqlogger.Entry, matchers ...Matcher) {
+	t.Helper()
+	for _, m := range matchers {
+		if len(Find(entries, m)) == 0 {
+			t.Errorf("no entry matches %s in:\n%s", m, listEntries(entries))
+		}
+	}
+}
+
+// AssertNotLogged fails the test if any of the matchers selects an entry.
+func AssertNotLogged(t testing.TB, entries []sqlogger.Entry, matchers ...Matcher) {
+	t.Helper()
+	for _, m := range matchers {
+		if found := Find(entries, m); len(found) > 0 {
+			t.Errorf("unexpected entries match %s:\n%s", m, listEntries(found))
+		}
+	}
+}
+
+// normalized returns the value in a form which compares equal to the value it
+// is stored as, with integers as int64 and times in UTC.
+func normalized(v slog.Value) any {
+	v = v.Resolve()
+	switch v.Kind() {
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		if u := v.Uint64(); u <= 1<<63-1 {
+			return int64(u)
+		}
+		return v.String()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration()
+	case slog.KindTime:
+		return v.Time().UTC().Format(time.RFC3339Nano)
+	default:
+		return v.String()
+	}
+}
+
+// listEntries renders the entries for the failure messages.
+func listEntries(entries []sqlogger.Entry) string {
+	if len(entries) == 0 {
+		return "\t(no entries)"
+	}
+	var b strings.Builder
+	for _, e := range entries {
+		text := e.Content
+		if text == "" {
+			text = e.Message
+		}
+		fmt.Fprintf(&b, "\t%s\n", strings.TrimSpace(text))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
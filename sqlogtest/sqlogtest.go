@@ -0,0 +1,212 @@
+// Package sqlogtest helps testing the applications which log with sqlogger: it
+// provides a logger writing to an in-memory database and the helpers to assert
+// on what was logged, both as seen by the sinks and as stored in the database.
+//
+//	func TestPlaceOrder(t *testing.T) {
+//		log := sqlogtest.New(t, nil)
+//		placeOrder(log.Logger, 42)
+//		log.AssertLogged(t, sqlogtest.HasEntry(slog.LevelInfo, "order placed", "order_id", 42))
+//	}
+package sqlogtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hesusruiz/sqlogger"
+)
+
+// Logger is a logger for tests, writing to an in-memory database with the
+// attrs table enabled, so the stored attributes can be checked too.
+type Logger struct {
+	*slog.Logger
+
+	// Handler is the handler of the logger.
+	Handler *sqlogger.SQLogger
+
+	// DB is the in-memory database of the store.
+	DB *sql.DB
+
+	// Capture receives every entry sent to the sinks, with the message and the
+	// attributes as they were logged.
+	Capture *Capture
+}
+
+// New returns a Logger which is closed when the test ends. The options, which may
+// be nil, can not set a custom Store. If they do not set a Console, the console
+// output goes to t.Log, so it is shown only for failed tests or with -v.
+func New(t testing.TB, opts *sqlogger.Options) *Logger {
+	t.Helper()
+
+	var o sqlogger.Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Console == nil {
+		o.Console = testWriter{t}
+	}
+	o.AttrTable = true
+
+	capture := &Capture{}
+	o.Sinks = append(o.Sinks[:len(o.Sinks):len(o.Sinks)], capture)
+
+	db, err := sql.Open("sqlite3", sqlogger.MemoryDir)
+	if err != nil {
+		t.Fatalf("opening the in-memory database: %v", err)
+	}
+	// Every connection to :memory: is a different database
+	db.SetMaxOpenConns(1)
+
+	h, err := sqlogger.NewSQLoggerWithDB(db, &o)
+	if err != nil {
+		db.Close()
+		t.Fatalf("creating the logger: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := h.Close(); err != nil {
+			t.Errorf("closing the logger: %v", err)
+		}
+		db.Close()
+	})
+
+	return &Logger{
+		Logger:  slog.New(h),
+		Handler: h,
+		DB:      db,
+		Capture: capture,
+	}
+}
+
+// ReadAll returns the entries stored in the live unit of the database, with the
+// attributes read back from the attrs table. The stored entries have no Message,
+// so the matchers check their Content instead.
+func (l *Logger) ReadAll(t testing.TB) []sqlogger.Entry {
+	t.Helper()
+
+	entries, err := l.Handler.Store().Query(context.Background(), sqlogger.Query{})
+	if err != nil {
+		t.Fatalf("reading the log entries: %v", err)
+	}
+
+	attrs, err := readAttrs(l.DB)
+	if err != nil {
+		t.Fatalf("reading the log attributes: %v", err)
+	}
+	for i := range entries {
+		entries[i].Attrs = attrs[entries[i].ID]
+	}
+
+	return entries
+}
+
+// AssertLogged fails the test if any of the matchers selects none of the stored
+// entries.
+func (l *Logger) AssertLogged(t testing.TB, matchers ...Matcher) {
+	t.Helper()
+	AssertLogged(t, l.ReadAll(t), matchers...)
+}
+
+// AssertNotLogged fails the test if any of the matchers selects a stored entry.
+func (l *Logger) AssertNotLogged(t testing.TB, matchers ...Matcher) {
+	t.Helper()
+	AssertNotLogged(t, l.ReadAll(t), matchers...)
+}
+
+// readAttrs returns the attributes of the attrs table, by the rowid of their entry.
+func readAttrs(db *sql.DB) (map[int64][]slog.Attr, error) {
+	rows, err := db.Query(`SELECT entry_id, key, value_type, value FROM attrs ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attrs := map[int64][]slog.Attr{}
+	for rows.Next() {
+		var id int64
+		var key, valueType string
+		var value any
+		if err := rows.Scan(&id, &key, &valueType, &value); err != nil {
+			return nil, err
+		}
+		attrs[id] = append(attrs[id], slog.Attr{Key: key, Value: storedValue(valueType, value)})
+	}
+
+	return attrs, rows.Err()
+}
+
+// storedValue converts a value of the attrs table back to the kind it was logged with.
+func storedValue(valueType string, value any) slog.Value {
+	switch v := value.(type) {
+	case int64:
+		switch valueType {
+		case "bool":
+			return slog.BoolValue(v != 0)
+		case "duration":
+			return slog.DurationValue(time.Duration(v))
+		}
+		return slog.Int64Value(v)
+	case float64:
+		return slog.Float64Value(v)
+	case []byte:
+		value = string(v)
+	}
+
+	s := fmt.Sprint(value)
+	if valueType == "time" {
+		if tm, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return slog.TimeValue(tm)
+		}
+	}
+	return slog.StringValue(s)
+}
+
+// testWriter writes the console output to the log of the test.
+type testWriter struct {
+	t testing.TB
+}
+
+func (w testWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// Capture is a sqlogger.Sink keeping the entries in memory, to assert on the
+// messages and the attributes as they were logged.
+type Capture struct {
+	mu      sync.Mutex
+	entries []sqlogger.Entry
+}
+
+var _ sqlogger.Sink = (*Capture)(nil)
+
+func (c *Capture) Send(e *sqlogger.Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, *e)
+	return nil
+}
+
+func (c *Capture) Close() error {
+	return nil
+}
+
+// Entries returns the entries received so far.
+func (c *Capture) Entries() []sqlogger.Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]sqlogger.Entry(nil), c.entries...)
+}
+
+// Reset discards the entries received so far.
+func (c *Capture) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
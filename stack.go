@@ -0,0 +1,182 @@
+package sqlogger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strconv"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// ErrorChainSuffix is appended to the key of an error attribute to name the
+// attribute holding the messages of the errors it wraps, with Options.ExpandErrors.
+const ErrorChainSuffix = ".chain"
+
+// maxStackDepth is the maximum number of frames captured by WithStack.
+const maxStackDepth = 64
+
+// stackTracer is implemented by the errors of github.com/pkg/errors.
+type stackTracer interface {
+	StackTrace() pkgerrors.StackTrace
+}
+
+// callersError is implemented by errors which captured the program counters of
+// the stack where they were created, like those returned by WithStack.
+type callersError interface {
+	Callers() []uintptr
+}
+
+// stackError is an error with the stack where it was wrapped by WithStack.
+type stackError struct {
+	err error
+	pcs []uintptr
+}
+
+func (e *stackError) Error() string      { return e.err.Error() }
+func (e *stackError) Unwrap() error      { return e.err }
+func (e *stackError) Callers() []uintptr { return e.pcs }
+
+// WithStack returns err annotated with the stack of the caller, which is stored
+// and printed when the error is logged with Options.ExpandErrors.
+// It returns nil if err is nil.
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(2, pcs)
+	return &stackError{err: err, pcs: pcs[:n]}
+}
+
+// errorCallers returns the program counters of the innermost stack carried by the
+// errors in the chain of err, which is the closest to the origin of the error.
+func errorCallers(err error) []uintptr {
+	var pcs []uintptr
+	walkErrors(err, func(e error) {
+		switch e := e.(type) {
+		case callersError:
+			pcs = e.Callers()
+		case stackTracer:
+			st := e.StackTrace()
+			pcs = make([]uintptr, len(st))
+			for i, f := range st {
+				pcs[i] = uintptr(f)
+			}
+		}
+	})
+	return pcs
+}
+
+// errorChain returns the messages of the errors wrapped by err, outermost first.
+// Wrappers which do not change the message, like those adding a stack, are skipped.
+func errorChain(err error) []string {
+	var chain []string
+	last := err.Error()
+	walkErrors(err, func(e error) {
+		if msg := e.Error(); msg != last {
+			chain = append(chain, msg)
+			last = msg
+		}
+	})
+	return chain
+}
+
+// walkErrors calls f for err and all the errors in its tree, depth first.
+func walkErrors(err error, f func(error)) {
+	if err == nil {
+		return
+	}
+	f(err)
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		walkErrors(e.Unwrap(), f)
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			walkErrors(inner, f)
+		}
+	}
+}
+
+// expandErrors looks for the first error in the attributes of the record, and
+// returns the record with the chain of the error added, and its stack.
+func (h *SQLogger) expandErrors(r slog.Record) (slog.Record, []slog.Source) {
+	var key string
+	var err error
+	r.Attrs(func(a slog.Attr) bool {
+		if e, ok := a.Value.Resolve().Any().(error); ok && a.Value.Kind() == slog.KindAny {
+			key, err = a.Key, e
+			return false
+		}
+		return true
+	})
+	if err == nil {
+		return r, nil
+	}
+
+	if chain := errorChain(err); len(chain) > 0 {
+		r = r.Clone()
+		r.AddAttrs(slog.Any(key+ErrorChainSuffix, chain))
+	}
+
+	var stack []slog.Source
+	pcs := errorCallers(err)
+	if len(pcs) > 0 {
+		frames := runtime.CallersFrames(pcs)
+		for {
+			f, more := frames.Next()
+			// The frames of the runtime starting the goroutine are noise
+			if f.Function != "" && f.Function != "runtime.main" && f.Function != "runtime.goexit" {
+				stack = append(stack, slog.Source{Function: f.Function, File: h.relativeFile(f.File), Line: f.Line})
+			}
+			if !more {
+				break
+			}
+		}
+	}
+
+	return r, stack
+}
+
+// encodeStack returns the JSON encoding of a stack trace stored in the stack
+// column, or nil if the stack is empty.
+func encodeStack(stack []slog.Source) (any, error) {
+	if len(stack) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(stack)
+	if err != nil {
+		return nil, fmt.Errorf("encoding stack trace: %w", err)
+	}
+	return string(data), nil
+}
+
+// DecodeStack decodes the JSON array of frames stored in the stack column.
+// It returns nil for an empty string.
+func DecodeStack(s string) ([]slog.Source, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var stack []slog.Source
+	if err := json.Unmarshal([]byte(s), &stack); err != nil {
+		return nil, fmt.Errorf("decoding stack trace: %w", err)
+	}
+	return stack, nil
+}
+
+// appendStack renders a stack trace for the console, one frame per line.
+func (h *SQLogger) appendStack(buf []byte, stack []slog.Source) []byte {
+	for _, f := range stack {
+		buf = append(buf, "    at "...)
+		buf = append(buf, f.Function...)
+		buf = append(buf, " ("...)
+		buf = append(buf, h.palette.source.start...)
+		buf = append(buf, f.File...)
+		buf = append(buf, ':')
+		buf = strconv.AppendInt(buf, int64(f.Line), 10)
+		buf = append(buf, h.palette.source.end...)
+		buf = append(buf, ")\n"...)
+	}
+	return buf
+}
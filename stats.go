@@ -0,0 +1,168 @@
+package sqlogger
+
+import (
+	"expvar"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// latencySamples is the number of recent insert latencies kept to compute percentiles.
+const latencySamples = 1024
+
+// metrics are the internal counters of the handler, shared by all the handlers
+// derived from the same SQLogger.
+type metrics struct {
+	mu         sync.Mutex
+	byLevel    map[slog.Level]uint64
+	latencies  [latencySamples]time.Duration
+	numSamples int
+	nextSample int
+	inserts    uint64
+	latencySum time.Duration
+	rows       int64
+	rotations  uint64
+	errors     uint64
+	sinkErrors uint64
+}
+
+// Stats is a snapshot of the internal metrics of the handler, to monitor the
+// health of the logging itself.
+type Stats struct {
+	// EntriesByLevel is the number of entries written to the store per level name,
+	// as printed by the handler, including the names of Options.LevelNames.
+	EntriesByLevel map[string]uint64
+
+	// InsertLatency summarizes the duration of the recent inserts in the store.
+	InsertLatency LatencyStats
+
+	// CurrentFile is the name of the live storage unit, and CurrentRows the number
+	// of entries inserted in it by this process.
+	CurrentFile string
+	CurrentRows int64
+
+	// Rotations is the number of rotations performed.
+	Rotations uint64
+
+	// Errors is the number of failed inserts in the store, and SinkErrors the number
+	// of entries which could not be sent to a sink.
+	Errors     uint64
+	SinkErrors uint64
+
+	// Deferred is the number of entries waiting in the retry queue, and Dropped the
+	// number of entries lost because the queue was full.
+	Deferred int
+	Dropped  uint64
+
+	// ConsoleDropped is the number of console lines lost because the queue of
+	// Options.AsyncConsole was full. The entries are still in the store.
+	ConsoleDropped uint64
+}
+
+// LatencyStats are percentiles of the recent durations, with the count and sum of
+// all of them.
+type LatencyStats struct {
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+
+	Count uint64
+	Sum   time.Duration
+}
+
+func newMetrics() *metrics {
+	return &metrics{byLevel: map[slog.Level]uint64{}}
+}
+
+// observeInsert records the result of an insert in the store.
+func (m *metrics) observeInsert(level slog.Level, latency time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.latencies[m.nextSample] = latency
+	m.nextSample = (m.nextSample + 1) % latencySamples
+	m.numSamples = min(m.numSamples+1, latencySamples)
+	m.inserts++
+	m.latencySum += latency
+
+	if err != nil {
+		m.errors++
+		return
+	}
+	m.byLevel[level]++
+}
+
+func (m *metrics) observeRows(rows int64) {
+	m.mu.Lock()
+	m.rows = rows
+	m.mu.Unlock()
+}
+
+func (m *metrics) observeRotation() {
+	m.mu.Lock()
+	m.rotations++
+	m.rows = 0
+	m.mu.Unlock()
+}
+
+func (m *metrics) observeSinkError() {
+	m.mu.Lock()
+	m.sinkErrors++
+	m.mu.Unlock()
+}
+
+// Stats returns a snapshot of the internal metrics of the handler.
+func (h *SQLogger) Stats() Stats {
+	m := h.metrics
+	m.mu.Lock()
+
+	stats := Stats{
+		EntriesByLevel: make(map[string]uint64, len(m.byLevel)),
+		CurrentRows:    m.rows,
+		Rotations:      m.rotations,
+		Errors:         m.errors,
+		SinkErrors:     m.sinkErrors,
+	}
+	for level, n := range m.byLevel {
+		stats.EntriesByLevel[h.levelName(level)] += n
+	}
+	stats.InsertLatency.Count = m.inserts
+	stats.InsertLatency.Sum = m.latencySum
+	samples := slices.Clone(m.latencies[:m.numSamples])
+
+	m.mu.Unlock()
+
+	if len(samples) > 0 {
+		slices.Sort(samples)
+		percentile := func(p int) time.Duration {
+			return samples[(len(samples)-1)*p/100]
+		}
+		stats.InsertLatency.P50 = percentile(50)
+		stats.InsertLatency.P90 = percentile(90)
+		stats.InsertLatency.P99 = percentile(99)
+		stats.InsertLatency.Max = samples[len(samples)-1]
+	}
+
+	stats.CurrentFile = h.store.CurrentName()
+
+	failures := h.WriteFailures()
+	stats.Deferred = failures.Deferred
+	stats.Dropped = failures.Dropped
+
+	if h.asyncConsole != nil {
+		stats.ConsoleDropped = h.asyncConsole.dropped.Load()
+	}
+
+	return stats
+}
+
+// PublishExpvar publishes the Stats of the handler as an expvar variable with the
+// given name, available at /debug/vars. Like expvar.Publish, it panics if the name
+// is already in use.
+func (h *SQLogger) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return h.Stats()
+	}))
+}
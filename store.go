@@ -0,0 +1,139 @@
+package sqlogger
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Entry is a log record in the form it is persisted by a Store.
+type Entry struct {
+	// ID is assigned by the Store when the entry is inserted.
+	ID int64
+
+	// File is the database file containing the entry, for the entries returned
+	// by a Reader.
+	File string
+
+	Time  time.Time
+	Level slog.Level
+
+	// Content is the rendered log line, without any color decoration.
+	Content string
+
+	// Message is the message of the record.
+	Message string
+
+	// Attrs are the attributes of the record and the handler, with the keys
+	// qualified by their groups. They are only filled for the Sinks and for the
+	// attribute storage of the default store.
+	Attrs []slog.Attr
+
+	// RepeatCount is the number of consecutive times the entry was logged, which
+	// is more than one only when repeats are coalesced (see Options.CoalesceRepeats),
+	// and LastTime is the time of the last repetition.
+	RepeatCount int64
+	LastTime    time.Time
+
+	// Source is the location of the log call, with the file relative to the working
+	// directory. It is nil if the record has no location or Options.NoSource is set.
+	Source *slog.Source
+
+	// Fingerprint identifies the log call, as a hash of its function and of the
+	// message with the numbers masked, so the entries of the same error site are
+	// grouped across restarts and releases (see Reader.TopFingerprints).
+	Fingerprint string
+
+	// Stack is the stack trace of the error logged with the entry, innermost frame
+	// first, when Options.ExpandErrors is set and the error carries one.
+	Stack []slog.Source
+
+	// Blobs are the full values of the message and the attributes truncated by the
+	// size limits, for the blobs table of the default store (see Options.BlobTable).
+	Blobs []slog.Attr
+}
+
+// Query selects entries from a Store. The zero value selects all entries.
+type Query struct {
+	// Since and Until limit the entries to the given time range.
+	// A zero value means no limit on that side.
+	Since time.Time
+	Until time.Time
+
+	// MinLevel selects only entries with a level greater or equal than this.
+	// If nil, entries of all levels are selected.
+	MinLevel slog.Leveler
+
+	// Contains selects only entries whose content contains this string.
+	Contains string
+
+	// SourceFile selects only entries logged from this file, relative to the
+	// working directory of the process, like "store/orders.go".
+	SourceFile string
+
+	// Attrs selects only entries with all these attributes, with the keys qualified
+	// by their groups like "req.user_id". The values must have the type they were
+	// logged with, like 42 for slog.Int("user_id", 42).
+	// Attributes with their own column are selected by it (see Options.IndexedAttrs),
+	// and the others require the attrs table (see Options.AttrTable).
+	Attrs map[string]any
+
+	// Limit is the maximum number of entries returned. Zero means no limit.
+	Limit int
+}
+
+// Repeater is implemented by stores which can record that the last entry inserted
+// was repeated, instead of inserting it again.
+type Repeater interface {
+	// RepeatLast sets the total number of times the last entry inserted was logged,
+	// and the time of the last repetition.
+	RepeatLast(count int64, last time.Time) error
+}
+
+// EntryCounter is implemented by stores which can count the entries of the live
+// storage unit, so the handler rotates at the right number of entries when the
+// unit already has some, like after a restart. SQLiteStore does not need it, as
+// its live file is always new when it is opened.
+type EntryCounter interface {
+	CountLive() (int64, error)
+}
+
+// ContextInserter is implemented by stores which can abandon an insert when a
+// context is done, so a locked database does not block the logging goroutines.
+type ContextInserter interface {
+	InsertContext(ctx context.Context, e Entry) (int64, error)
+}
+
+// HealthChecker is implemented by stores which can check that they are able to
+// accept new entries.
+type HealthChecker interface {
+	Healthy(ctx context.Context) error
+}
+
+// Store is the storage backend of the handler.
+// The default is a ring of SQLite database files (see SQLiteStore), but any
+// other relational store can be plugged in via Options.Store.
+type Store interface {
+	// Open prepares the store for writing, creating the schema if needed.
+	Open() error
+
+	// Insert writes an entry to the live storage unit (the current file for SQLite).
+	// It returns the identifier of the entry inside the live storage unit, which
+	// is positive.
+	Insert(e Entry) (int64, error)
+
+	// Rotate seals the live storage unit and starts a new one, discarding the
+	// oldest one if the maximum number of units has been reached.
+	Rotate() error
+
+	// CurrentName returns the name of the live storage unit, which for SQLite
+	// is the path of the database file.
+	CurrentName() string
+
+	// Query returns the entries of the live storage unit selected by q, in
+	// chronological order.
+	Query(ctx context.Context, q Query) ([]Entry, error)
+
+	// Close releases the resources used by the store.
+	Close() error
+}
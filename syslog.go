@@ -0,0 +1,43 @@
+//go:build !windows && !plan9
+
+package sqlogger
+
+import (
+	"log/syslog"
+)
+
+// SyslogSink forwards the entries to the local syslog daemon.
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink connects to the local syslog daemon, tagging the messages with tag.
+// If tag is empty, the program name is used.
+func NewSyslogSink(tag string) (*SyslogSink, error) {
+	w, err := syslog.New(syslog.LOG_USER|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSink{w: w}, nil
+}
+
+func (s *SyslogSink) Send(e *Entry) error {
+	msg := sinkText(e)
+
+	switch severity(e.Level) {
+	case severityDebug:
+		return s.w.Debug(msg)
+	case severityInfo:
+		return s.w.Info(msg)
+	case severityWarning:
+		return s.w.Warning(msg)
+	case severityError:
+		return s.w.Err(msg)
+	default:
+		return s.w.Crit(msg)
+	}
+}
+
+func (s *SyslogSink) Close() error {
+	return s.w.Close()
+}
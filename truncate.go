@@ -0,0 +1,187 @@
+package sqlogger
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"unicode/utf8"
+)
+
+// TruncatedKey is the key of the attribute added to the records whose message or
+// attributes were truncated by Options.MaxMessageBytes or Options.MaxAttrBytes.
+const TruncatedKey = "truncated"
+
+// With the blobs table enabled, the full values of the message and the attributes
+// truncated are stored in it, keyed by the rowid of the entry.
+const blobTableSQL = `
+DROP TABLE IF EXISTS blobs;
+
+CREATE TABLE blobs (
+  entry_id INTEGER NOT NULL,
+  key TEXT NOT NULL,
+  value BLOB
+);
+
+CREATE INDEX blobs_entry_id ON blobs (entry_id);
+`
+
+// truncateUTF8 returns the longest prefix of s with at most max bytes which does
+// not split a multi-byte character.
+func truncateUTF8(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
+
+// binaryValue returns the bytes of a []byte value.
+func binaryValue(v slog.Value) ([]byte, bool) {
+	if v.Kind() != slog.KindAny {
+		return nil, false
+	}
+	b, ok := v.Any().([]byte)
+	return b, ok
+}
+
+// truncateValue returns the value truncated to max bytes, or to maxBinary bytes
+// for []byte values, and its full value if it was truncated. Other values of kind
+// Any are truncated by their text representation. A limit of zero does not truncate.
+func truncateValue(v slog.Value, max int, maxBinary int) (slog.Value, slog.Value, bool) {
+	v = v.Resolve()
+	if b, ok := binaryValue(v); ok {
+		if maxBinary <= 0 || len(b) <= maxBinary {
+			return v, slog.Value{}, false
+		}
+		return slog.AnyValue(b[:maxBinary:maxBinary]), v, true
+	}
+	if max <= 0 || (v.Kind() != slog.KindString && v.Kind() != slog.KindAny) {
+		return v, slog.Value{}, false
+	}
+	s := v.String()
+	if len(s) <= max {
+		return v, slog.Value{}, false
+	}
+	return slog.StringValue(truncateUTF8(s, max)), slog.StringValue(s), true
+}
+
+// isLargeBinary reports whether the value of a, or some value inside it if it is
+// a group, is a []byte longer than max bytes.
+func isLargeBinary(a slog.Attr, max int) bool {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		for _, ga := range v.Group() {
+			if isLargeBinary(ga, max) {
+				return true
+			}
+		}
+		return false
+	}
+	b, ok := binaryValue(v)
+	return ok && len(b) > max
+}
+
+// truncateAttrs truncates the values of the attributes, inside groups too, calling
+// full with the qualified key and the full value of each value truncated.
+func truncateAttrs(attrs []slog.Attr, prefix string, max int, maxBinary int, full func(key string, value slog.Value)) ([]slog.Attr, bool) {
+	var truncated bool
+	out := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		out[i] = a
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() == slog.KindGroup {
+			groupPrefix := prefix
+			if a.Key != "" {
+				groupPrefix += a.Key + "."
+			}
+			group, t := truncateAttrs(a.Value.Group(), groupPrefix, max, maxBinary, full)
+			if t {
+				out[i] = slog.Attr{Key: a.Key, Value: slog.GroupValue(group...)}
+				truncated = true
+			}
+			continue
+		}
+		if v, fullValue, t := truncateValue(a.Value, max, maxBinary); t {
+			out[i] = slog.Attr{Key: a.Key, Value: v}
+			if full != nil {
+				full(prefix+a.Key, fullValue)
+			}
+			truncated = true
+		}
+	}
+	return out, truncated
+}
+
+// truncate applies the size limits to the message and the attributes of a record,
+// returning the record and the full values truncated.
+func (h *SQLogger) truncate(r slog.Record) (slog.Record, []slog.Attr) {
+	var payloads []slog.Attr
+	var full func(key string, value slog.Value)
+	if h.storeBlobs {
+		full = func(key string, value slog.Value) {
+			payloads = append(payloads, slog.Attr{Key: key, Value: value})
+		}
+	}
+
+	msg := r.Message
+	truncated := false
+	if max := h.opts.MaxMessageBytes; max > 0 && len(msg) > max {
+		msg = truncateUTF8(msg, max)
+		if full != nil {
+			full(slog.MessageKey, slog.StringValue(r.Message))
+		}
+		truncated = true
+	}
+
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	var t bool
+	attrs, t = truncateAttrs(attrs, h.prefix, h.opts.MaxAttrBytes, h.opts.MaxBinaryAttrBytes, full)
+	truncated = truncated || t
+
+	if !truncated {
+		return r, nil
+	}
+
+	r2 := slog.NewRecord(r.Time, r.Level, msg, r.PC)
+	r2.AddAttrs(attrs...)
+	r2.AddAttrs(slog.Bool(TruncatedKey, true))
+	return r2, payloads
+}
+
+// hasLargeBinary reports whether the record has []byte values longer than
+// Options.MaxBinaryAttrBytes.
+func (h *SQLogger) hasLargeBinary(r slog.Record) bool {
+	var large bool
+	r.Attrs(func(a slog.Attr) bool {
+		large = isLargeBinary(a, h.opts.MaxBinaryAttrBytes)
+		return !large
+	})
+	return large
+}
+
+// blobColumnValue returns the value stored in the blobs table for a full value.
+func blobColumnValue(v slog.Value) any {
+	if b, ok := binaryValue(v); ok {
+		return b
+	}
+	return v.String()
+}
+
+// insertBlobs writes the full values of the truncated message and attributes of
+// the entry with the given rowid. The []byte values are stored as they are, and
+// the others by their text.
+func insertBlobs(ctx context.Context, tx *sql.Tx, id int64, payloads []slog.Attr) error {
+	for _, a := range payloads {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO blobs (entry_id, key, value) VALUES (?, ?, ?)", id, a.Key, blobColumnValue(a.Value)); err != nil {
+			return fmt.Errorf("inserting truncated payload: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package sqlogger
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const defaultWALCheckInterval = 10 * time.Second
+
+// startCheckpointer starts the background checkpoints of the WAL, if enabled.
+func (s *SQLiteStore) startCheckpointer() {
+	if s.walCheckpointEvery <= 0 && s.walSizeLimit <= 0 {
+		return
+	}
+
+	interval := s.walCheckpointEvery
+	if interval <= 0 {
+		interval = defaultWALCheckInterval
+	}
+
+	s.stopCheckpoints = make(chan struct{})
+	s.checkpointsDone = make(chan struct{})
+
+	go func() {
+		defer close(s.checkpointsDone)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.checkpoint()
+			case <-s.stopCheckpoints:
+				return
+			}
+		}
+	}()
+}
+
+// stopCheckpointer stops the background checkpoints and waits for the running one.
+func (s *SQLiteStore) stopCheckpointer() {
+	if s.stopCheckpoints == nil {
+		return
+	}
+	close(s.stopCheckpoints)
+	<-s.checkpointsDone
+	s.stopCheckpoints = nil
+}
+
+// checkpoint moves the WAL into the live database file: with a passive checkpoint
+// every walCheckpointEvery, which does not wait for the readers, and truncating
+// the WAL when it is larger than walSizeLimit. The error is reported by Healthy.
+func (s *SQLiteStore) checkpoint() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mode := ""
+	if s.walCheckpointEvery > 0 {
+		mode = "PASSIVE"
+	}
+	if info, err := os.Stat(s.currentName + "-wal"); err == nil && s.walSizeLimit > 0 && info.Size() > s.walSizeLimit {
+		mode = "TRUNCATE"
+	}
+	if mode == "" {
+		return
+	}
+
+	s.checkpointErr = nil
+	if _, err := s.db.Exec("PRAGMA wal_checkpoint(" + mode + ")"); err != nil {
+		s.checkpointErr = fmt.Errorf("checkpointing WAL of %s: %w", s.currentName, err)
+	}
+}
@@ -0,0 +1,78 @@
+package sqlogger
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Writer returns an io.Writer logging every line written to it as a record with
+// the given level, for the code which logs with the standard log package or to an
+// io.Writer:
+//
+//	log.SetFlags(0)
+//	log.SetOutput(handler.Writer(slog.LevelInfo))
+//
+// The location of the records is the caller of the log package, or of fmt for
+// calls like fmt.Fprintf.
+func (h *SQLogger) Writer(level slog.Level) io.Writer {
+	return &levelWriter{h: h, level: level}
+}
+
+// NewLogLogger returns a log.Logger writing to h with the given level, like the
+// ErrorLog of an http.Server.
+func NewLogLogger(h *SQLogger, level slog.Level) *log.Logger {
+	return log.New(h.Writer(level), "", 0)
+}
+
+type levelWriter struct {
+	h     *SQLogger
+	level slog.Level
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	ctx := context.Background()
+	if !w.h.Enabled(ctx, w.level) {
+		return len(p), nil
+	}
+
+	var pc uintptr
+	if !w.h.opts.NoSource {
+		pc = writerCaller()
+	}
+
+	for line := range bytes.Lines(p) {
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) == 0 {
+			continue
+		}
+		r := slog.NewRecord(time.Now(), w.level, string(line), pc)
+		if err := w.h.Handle(ctx, r); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// writerCaller returns the PC of the first caller outside of the log, fmt and io
+// packages, as a return address like those of runtime.Callers.
+func writerCaller() uintptr {
+	var pcs [16]uintptr
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "log.") && !strings.HasPrefix(f.Function, "fmt.") && !strings.HasPrefix(f.Function, "io.") {
+			return f.PC + 1
+		}
+		if !more {
+			return 0
+		}
+	}
+}